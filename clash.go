@@ -0,0 +1,117 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+// A Clash is an event on another stage overlapping with the event of a band.
+type Clash struct {
+	// Band is the event of the band the clashes were asked for.
+	Band *Event `json:"band"`
+
+	// BandStage is the label of the stage Band plays on.
+	BandStage string `json:"band_stage"`
+
+	// Day is the label of the day Band plays on.
+	Day string `json:"day"`
+
+	// Event is the event overlapping with Band.
+	Event *Event `json:"event"`
+
+	// Stage is the label of the stage Event takes place on.
+	Stage string `json:"stage"`
+
+	// OverlapMinutes is the number of minutes both events take place at the
+	// same time.
+	OverlapMinutes int `json:"overlap_minutes"`
+}
+
+// Clashes returns the events on other stages overlapping with the events of
+// bands. A band is given by its ID or its label, which is compared case
+// insensitively to the normalized and the original label of the events. The
+// clashes are returned in the order of the bands, and for every band in
+// document order. A band given more than once, e.g. by its ID and its label,
+// is only checked once.
+//
+// Events without an end can not be checked and are never part of a clash.
+func Clashes(ro *RunningOrder, bands ...string) []*Clash {
+	var clashes []*Clash
+	checked := make(map[*Event]bool)
+
+	for _, b := range bands {
+		for _, d := range ro.Days {
+			for _, s := range d.Stages {
+				for _, e := range s.Events {
+					if checked[e] || !isBand(e, b) {
+						continue
+					}
+					checked[e] = true
+
+					clashes = append(clashes, clashesOf(ro, d, s, e)...)
+				}
+			}
+		}
+	}
+
+	return clashes
+}
+
+// isBand returns true if the event e belongs to the band b, given by its ID or
+// label.
+func isBand(e *Event, b string) bool {
+	if e.ID != "" && e.ID == b {
+		return true
+	}
+
+	k := dictionaryKey(b)
+	if k == "" {
+		return false
+	}
+
+	return k == dictionaryKey(e.Label) || k == dictionaryKey(e.OriginalLabel)
+}
+
+// clashesOf returns the events of ro on other stages than s overlapping with
+// the event e on stage s on day d.
+func clashesOf(ro *RunningOrder, d *Day, s *Stage, e *Event) []*Clash {
+	var clashes []*Clash
+
+	for _, od := range ro.Days {
+		for _, st := range od.Stages {
+			if st.Label == s.Label {
+				continue
+			}
+
+			for _, oe := range st.Events {
+				m := overlap(e.TimeStamps, oe.TimeStamps)
+				if m <= 0 {
+					continue
+				}
+
+				clashes = append(clashes, &Clash{e, s.Label, d.Label, oe, st.Label, int(m / 60)})
+			}
+		}
+	}
+
+	return clashes
+}
+
+// overlap returns the number of seconds a and b overlap. If a or b has no end,
+// 0 is returned.
+func overlap(a, b *TimeStamps) int64 {
+	if a == nil || b == nil || a.End == 0 || b.End == 0 {
+		return 0
+	}
+
+	start, end := a.Start, a.End
+	if b.Start > start {
+		start = b.Start
+	}
+	if b.End < end {
+		end = b.End
+	}
+
+	return end - start
+}
@@ -0,0 +1,77 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import "testing"
+
+func TestClashes(t *testing.T) {
+	event := func(label, id string, start, end int64) *Event {
+		return &Event{"", &TimeStamps{Start: start, End: end}, label, label, "", id}
+	}
+
+	foo := event("Foo", "1", 1500748200, 1500753600)
+	bar := event("Bar", "2", 1500753600, 1500757200)
+	baz := event("Baz", "3", 1500750000, 1500751800)
+	qux := event("Qux", "4", 1500752700, 1500756300)
+	open := event("Open", "5", 1500749100, 0)
+	late := event("Late", "6", 1500757200, 1500760800)
+	none := &Event{"-", nil, "None", "NONE", "", "7"}
+
+	ro := &RunningOrder{Days: []*Day{
+		{"Saturday 22.07.", []*Stage{
+			{"Main Stage", []*Event{foo, bar}, StageKindMain, 1},
+			{"Second Stage", []*Event{baz, qux, open}, StageKindSecond, 2},
+		}, nil, nil, false, nil},
+		{"Sunday 23.07.", []*Stage{
+			{"Second Stage", []*Event{late, none}, StageKindSecond, 1},
+		}, nil, nil, false, nil},
+	}}
+
+	type clash struct {
+		band, event *Event
+		minutes     int
+	}
+
+	ts := []struct {
+		name     string
+		bands    []string
+		expected []clash
+	}{
+		{"label", []string{"foo"}, []clash{{foo, baz, 30}, {foo, qux, 15}}},
+		{"id", []string{"2"}, []clash{{bar, qux, 45}}},
+		{"original_label", []string{"NONE"}, nil},
+		{"no_overlap_on_other_day", []string{"Late"}, nil},
+		{"open_end", []string{"Open"}, nil},
+		{"multiple", []string{"Qux", " FOO "}, []clash{{qux, foo, 15}, {qux, bar, 45}, {foo, baz, 30}, {foo, qux, 15}}},
+		{"unknown", []string{"Unknown", ""}, nil},
+		{"dup", []string{"Foo", "1"}, []clash{{foo, baz, 30}, {foo, qux, 15}}},
+		{"dup_folded", []string{"foo", " FOO ", "Qux"}, []clash{{foo, baz, 30}, {foo, qux, 15}, {qux, foo, 15}, {qux, bar, 45}}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			cs := Clashes(ro, test.bands...)
+
+			if len(cs) != len(test.expected) {
+				t.Fatalf("unexpected number of clashes; is %d; expected %d", len(cs), len(test.expected))
+			}
+			for i, c := range cs {
+				e := test.expected[i]
+				if c.Band != e.band || c.Event != e.event || c.OverlapMinutes != e.minutes {
+					t.Errorf("unexpected clash %d; is %s/%s (%d); expected %s/%s (%d)", i,
+						c.Band.Label, c.Event.Label, c.OverlapMinutes,
+						e.band.Label, e.event.Label, e.minutes)
+				}
+			}
+		})
+	}
+
+	c := Clashes(ro, "Foo")[0]
+	if c.Day != "Saturday 22.07." || c.BandStage != "Main Stage" || c.Stage != "Second Stage" {
+		t.Errorf("unexpected location of clash: %q, %q, %q", c.Day, c.BandStage, c.Stage)
+	}
+}
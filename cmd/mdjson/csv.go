@@ -0,0 +1,123 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/blabber/mdjson"
+)
+
+// The output formats supported by dump.
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatTSV  = "tsv"
+)
+
+// csvContentTypes maps the CSV formats to their content types.
+var csvContentTypes = map[string]string{
+	formatCSV: "text/csv; charset=utf-8",
+	formatTSV: "text/tab-separated-values; charset=utf-8",
+}
+
+// newCSVEncoder returns a mdjson.CSVEncoder writing to w in format, which is
+// formatCSV or formatTSV. columns is a comma separated list of columns and
+// timeFormat a time package layout or mdjson.TimeFormatUnix. Empty values
+// select the defaults of mdjson.CSVEncoder.
+func newCSVEncoder(w io.Writer, format, columns, timeFormat string, order mdjson.Order) (*mdjson.CSVEncoder, error) {
+	var enc *mdjson.CSVEncoder
+	switch format {
+	case formatCSV:
+		enc = mdjson.NewCSVEncoder(w)
+	case formatTSV:
+		enc = mdjson.NewTSVEncoder(w)
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+
+	cs, err := mdjson.ParseColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	enc.SetColumns(cs...)
+
+	if timeFormat != "" {
+		enc.SetTimeFormat(timeFormat)
+	}
+	enc.SetOrder(order)
+
+	return enc, nil
+}
+
+// dumpCSV parses the latest running order found at URL u and writes it to w in
+// flags.format, which is formatCSV or formatTSV, using flags.columns and
+// flags.timeFormat.
+func dumpCSV(u string, w io.Writer, flags flags) error {
+	enc, err := newCSVEncoder(w, *flags.format, *flags.columns, *flags.timeFormat, mdjson.OrderDocument)
+	if err != nil {
+		return err
+	}
+
+	ro, _, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(ro)
+}
+
+// csvHandler returns a http.HandlerFunc that serves the latest running order
+// found at URL u in format, which is formatCSV or formatTSV. The columns, the
+// time format and the order of the events are given by the "columns",
+// "timeformat" and "order" query parameters, the events can be restricted
+// using the "stage" and "band" query parameters (see filter).
+//
+// Errors are served as plain text. If flags.cors is true, a wildcard
+// Access-Control-Allow-Origin is added to the response.
+func csvHandler(u string, format string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s request received", format)
+
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		q := r.URL.Query()
+
+		order, err := mdjson.ParseOrder(q.Get("order"))
+		if err != nil {
+			log.Printf("csvHandler: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		enc, err := newCSVEncoder(w, format, q.Get("columns"), q.Get("timeformat"), order)
+		if err != nil {
+			log.Printf("csvHandler: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
+		if err != nil {
+			log.Printf("loadRunningorder: %v", err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", csvContentTypes[format])
+
+		err = enc.Encode(filter(ro, q))
+		if err != nil {
+			log.Printf("encode: %v", err)
+		}
+	}
+}
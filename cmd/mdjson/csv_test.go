@@ -0,0 +1,143 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const testdataValidCSV = "../../testdata/sample.csv"
+
+func TestDumpCSV(t *testing.T) {
+	expectedCSV, err := ioutil.ReadFile(testdataValidCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := []struct {
+		name       string
+		format     string
+		columns    string
+		timeFormat string
+		validData  bool
+		expected   string
+	}{
+		{"csv", formatCSV, "", "", true, string(expectedCSV)},
+		{
+			"tsv",
+			formatTSV, "band,start", "15:04",
+			true,
+			"band\tstart\nTytus\t\nTurbowarrior Of Steel\t\nAmon Amarth\t22:30\nKatatonia\t20:45\nKadavar\t00:10\nDoro\t22:30\n",
+		},
+		{"invalid_format", "xml", "", "", false, ""},
+		{"invalid_columns", formatCSV, "foo", "", false, ""},
+	}
+
+	for _, ct := range ts {
+		t.Run(ct.name, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			fl := newSampleFlags()
+			*fl.format = ct.format
+			*fl.columns = ct.columns
+			*fl.timeFormat = ct.timeFormat
+
+			var b bytes.Buffer
+			err = dump(s.URL, &b, fl)
+			if (err == nil) != ct.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if b.String() != ct.expected {
+				t.Errorf("dump wrote unexpected data; expected: %q; is: %q", ct.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestServeCSV(t *testing.T) {
+	ts := []struct {
+		name        string
+		format      string
+		query       string
+		inputData   string
+		code        int
+		contentType string
+		expected    string
+	}{
+		{
+			"csv",
+			formatCSV,
+			"?columns=band,start,end&timeformat=unix&order=start&stage=main",
+			testdataValidHTML,
+			http.StatusOK,
+			"text/csv; charset=utf-8",
+			"band,start,end\nKatatonia,1501008300,1501012800\nAmon Amarth,1501014600,1501020000\nDoro,1501101000,1501106400\n",
+		},
+		{
+			"tsv",
+			formatTSV,
+			"?columns=day,band&band=Kadavar",
+			testdataValidHTML,
+			http.StatusOK,
+			"text/tab-separated-values; charset=utf-8",
+			"day\tband\nTuesday 25.07.\tKadavar\n",
+		},
+		{"invalid_columns", formatCSV, "?columns=foo", testdataValidHTML, http.StatusBadRequest, "text/plain; charset=utf-8", ""},
+		{"invalid_order", formatCSV, "?order=foo", testdataValidHTML, http.StatusBadRequest, "text/plain; charset=utf-8", ""},
+		{"invalid_data", formatCSV, "", testdataInvalidHTML, http.StatusInternalServerError, "text/plain; charset=utf-8", ""},
+	}
+
+	for _, ct := range ts {
+		t.Run(ct.name, func(t *testing.T) {
+			f, err := os.Open(ct.inputData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/runningorder."+ct.format+ct.query, nil)
+			rw := httptest.NewRecorder()
+			csvHandler(s.URL, ct.format, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != ct.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", ct.code, r.StatusCode)
+			}
+			if is := r.Header.Get("Content-Type"); is != ct.contentType {
+				t.Errorf("unexpected Content-Type; expected: %q; is: %q", ct.contentType, is)
+			}
+
+			if ct.code != http.StatusOK {
+				return
+			}
+
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != ct.expected {
+				t.Errorf("unexpected data; expected: %q; is: %q", ct.expected, b)
+			}
+		})
+	}
+}
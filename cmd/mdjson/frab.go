@@ -0,0 +1,96 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/blabber/mdjson"
+)
+
+// The frab schedule formats supported by dump.
+const (
+	formatFrabXML  = "frab-xml"
+	formatFrabJSON = "frab-json"
+)
+
+// frabContentTypes maps the frab schedule formats to their content types.
+var frabContentTypes = map[string]string{
+	formatFrabXML:  "application/xml; charset=utf-8",
+	formatFrabJSON: "application/json; charset=utf-8",
+}
+
+// newFrabEncoder returns a mdjson.FrabEncoder writing to w in format, which is
+// formatFrabXML or formatFrabJSON.
+func newFrabEncoder(w io.Writer, format string) (*mdjson.FrabEncoder, error) {
+	switch format {
+	case formatFrabXML:
+		return mdjson.NewFrabXMLEncoder(w), nil
+	case formatFrabJSON:
+		return mdjson.NewFrabJSONEncoder(w), nil
+	}
+
+	return nil, fmt.Errorf("invalid format %q", format)
+}
+
+// dumpFrab parses the latest running order found at URL u and writes its frab
+// schedule to w in flags.format, which is formatFrabXML or formatFrabJSON.
+func dumpFrab(u string, w io.Writer, flags flags) error {
+	enc, err := newFrabEncoder(w, *flags.format)
+	if err != nil {
+		return err
+	}
+
+	ro, _, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(ro)
+}
+
+// frabHandler returns a http.HandlerFunc that serves the frab schedule of the
+// latest running order found at URL u in format, which is formatFrabXML or
+// formatFrabJSON. The events can be restricted using the "stage" and "band"
+// query parameters (see filter).
+//
+// Errors are served as plain text, as schedule apps do not understand JSend.
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to
+// the response.
+func frabHandler(u string, format string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s request received", format)
+
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		enc, err := newFrabEncoder(w, format)
+		if err != nil {
+			log.Printf("frabHandler: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
+		if err != nil {
+			log.Printf("loadRunningorder: %v", err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", frabContentTypes[format])
+
+		err = enc.Encode(filter(ro, r.URL.Query()))
+		if err != nil {
+			log.Printf("encode: %v", err)
+		}
+	}
+}
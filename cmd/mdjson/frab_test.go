@@ -0,0 +1,139 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const (
+	testdataValidFrabXML  = "../../testdata/sample_schedule.xml"
+	testdataValidFrabJSON = "../../testdata/sample_schedule.json"
+)
+
+func TestDumpFrab(t *testing.T) {
+	ts := []struct {
+		name      string
+		format    string
+		inputData string
+		validData bool
+		expected  string
+	}{
+		{"xml", formatFrabXML, testdataValidHTML, true, testdataValidFrabXML},
+		{"json", formatFrabJSON, testdataValidHTML, true, testdataValidFrabJSON},
+		{"invalid_data", formatFrabXML, testdataInvalidHTML, false, ""},
+	}
+
+	for _, ft := range ts {
+		t.Run(ft.name, func(t *testing.T) {
+			f, err := os.Open(ft.inputData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			var b bytes.Buffer
+			fl := newSampleFlags()
+			*fl.format = ft.format
+			err = dump(s.URL, &b, fl)
+			if (err == nil) != ft.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !ft.validData {
+				return
+			}
+
+			expected, err := ioutil.ReadFile(ft.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b.Bytes(), expected) {
+				t.Errorf("dump wrote unexpected data; expected: %q; is: %q", expected, b.Bytes())
+			}
+		})
+	}
+}
+
+func TestServeFrab(t *testing.T) {
+	ts := []struct {
+		name        string
+		format      string
+		query       string
+		inputData   string
+		code        int
+		contentType string
+		events      int
+	}{
+		{"xml", formatFrabXML, "", testdataValidHTML, http.StatusOK, "application/xml; charset=utf-8", 0},
+		{"json", formatFrabJSON, "", testdataValidHTML, http.StatusOK, "application/json; charset=utf-8", 4},
+		{"json_filtered", formatFrabJSON, "?stage=main", testdataValidHTML, http.StatusOK, "application/json; charset=utf-8", 3},
+		{"invalid_data", formatFrabJSON, "", testdataInvalidHTML, http.StatusInternalServerError, "text/plain; charset=utf-8", 0},
+	}
+
+	for _, ft := range ts {
+		t.Run(ft.name, func(t *testing.T) {
+			f, err := os.Open(ft.inputData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/schedule"+ft.query, nil)
+			rw := httptest.NewRecorder()
+			frabHandler(s.URL, ft.format, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != ft.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", ft.code, r.StatusCode)
+			}
+			if is := r.Header.Get("Content-Type"); is != ft.contentType {
+				t.Errorf("unexpected Content-Type; expected: %q; is: %q", ft.contentType, is)
+			}
+
+			if ft.code != http.StatusOK || ft.format != formatFrabJSON {
+				return
+			}
+
+			var j struct {
+				Schedule struct {
+					Conference struct {
+						Days []struct {
+							Rooms map[string][]json.RawMessage `json:"rooms"`
+						} `json:"days"`
+					} `json:"conference"`
+				} `json:"schedule"`
+			}
+			err = json.NewDecoder(r.Body).Decode(&j)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n := 0
+			for _, d := range j.Schedule.Conference.Days {
+				for _, es := range d.Rooms {
+					n += len(es)
+				}
+			}
+			if n != ft.events {
+				t.Errorf("unexpected number of events; expected: %d; is: %d", ft.events, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blabber/mdjson"
+	"github.com/blabber/mdjson/history"
+)
+
+// errNoHistory is returned if the history is accessed, but no history is kept.
+var errNoHistory = errors.New("no history kept (see -history)")
+
+// snapshots is the data returned by historyHandler.
+type snapshots struct {
+	Snapshots []*history.Snapshot `json:"snapshots"`
+}
+
+// changelog is the data returned by changelogHandler.
+type changelog struct {
+	Changelog []*history.Entry `json:"changelog"`
+}
+
+// showHistory writes the snapshots in flags.store to w, one per line. If args
+// contains a hash, the running order with this hash is written to w like a
+// dump instead.
+func showHistory(args []string, w io.Writer, flags flags) error {
+	if flags.store == nil {
+		return errNoHistory
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("usage: mdjson history [hash]")
+	}
+
+	if len(args) == 1 {
+		ro, _, err := flags.store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(w)
+		return enc.Encode(newJsend(ro))
+	}
+
+	ss, err := flags.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range ss {
+		_, err = fmt.Fprintf(w, "%s %s\n", s.Time.Format(time.RFC3339), s.Hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// showChangelog writes the changelog of flags.store to w. Every entry starts
+// with a line containing the time and hash of its snapshot, followed by its
+// changes.
+func showChangelog(w io.Writer, flags flags) error {
+	if flags.store == nil {
+		return errNoHistory
+	}
+
+	es, err := flags.store.Changelog()
+	if err != nil {
+		return err
+	}
+
+	for i, e := range es {
+		if i > 0 {
+			_, err = fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "%s %s\n", e.Time.Format(time.RFC3339), e.Hash)
+		if err != nil {
+			return err
+		}
+
+		err = mdjson.WriteDiff(w, e.Changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// historyHandler returns a http.HandlerFunc that serves a JSON representation
+// of the snapshots in flags.store.
+//
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
+// response.
+func historyHandler(flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("history request received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		ss, err := flags.store.List()
+		if err != nil {
+			log.Printf("historyHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusInternalServerError))
+			return
+		}
+		if ss == nil {
+			ss = []*history.Snapshot{}
+		}
+
+		writeJsend(w, jsend{Status: "success", Data: snapshots{ss}})
+	}
+}
+
+// snapshotHandler returns a http.HandlerFunc that serves a JSON representation
+// of the running order in flags.store whose hash, or a unique prefix of it,
+// is given by the path "/history/<hash>.json".
+//
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
+// response.
+func snapshotHandler(flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("snapshot request received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		h := strings.TrimPrefix(r.URL.Path, "/history/")
+		if !strings.HasSuffix(h, ".json") || strings.Contains(h, "/") {
+			err := fmt.Errorf("invalid snapshot %q", h)
+			log.Printf("snapshotHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusNotFound))
+			return
+		}
+
+		ro, _, err := flags.store.Get(strings.TrimSuffix(h, ".json"))
+		if err != nil {
+			log.Printf("snapshotHandler: %v", err)
+
+			code := http.StatusInternalServerError
+			if err == history.ErrNotFound {
+				code = http.StatusNotFound
+			}
+			writeJsend(w, newJsendError(err, code))
+			return
+		}
+
+		writeJsend(w, newJsend(ro))
+	}
+}
+
+// changelogHandler returns a http.HandlerFunc that serves a JSON
+// representation of the changelog of flags.store.
+//
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
+// response.
+func changelogHandler(flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("changelog request received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		es, err := flags.store.Changelog()
+		if err != nil {
+			log.Printf("changelogHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusInternalServerError))
+			return
+		}
+		if es == nil {
+			es = []*history.Entry{}
+		}
+
+		writeJsend(w, jsend{Status: "success", Data: changelog{es}})
+	}
+}
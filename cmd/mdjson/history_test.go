@@ -0,0 +1,248 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blabber/mdjson/history"
+)
+
+// recordHistory opens a history store in a temporary directory and adds the
+// valid running order and a version with Kadavar moved to 00:30 to it. The
+// caller has to remove the directory.
+func recordHistory(t *testing.T) (flags, string) {
+	dir, err := ioutil.TempDir("", "mdjson-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := history.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl := newSampleFlags()
+	fl.store = s
+
+	html, err := ioutil.ReadFile(testdataValidHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := strings.Replace(string(html), "00:10 - 01:20", "00:30 - 01:40", 1)
+
+	for _, h := range []string{string(html), string(html), moved} {
+		srv := httptest.NewServer(dataHandler(strings.NewReader(h)))
+
+		var b bytes.Buffer
+		err = dump(srv.URL, &b, fl)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fl, dir
+}
+
+// dumpSample returns the dump of the sample running order without keeping a
+// history.
+func dumpSample(t *testing.T) []byte {
+	f, err := os.Open(testdataValidHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	srv := httptest.NewServer(dataHandler(f))
+	defer srv.Close()
+
+	var b bytes.Buffer
+	err = dump(srv.URL, &b, newSampleFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+func TestShowHistory(t *testing.T) {
+	fl, dir := recordHistory(t)
+	defer os.RemoveAll(dir)
+
+	ss, err := fl.store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 2 {
+		t.Fatalf("unexpected number of snapshots; expected: %d; is: %d", 2, len(ss))
+	}
+
+	var b bytes.Buffer
+	err = showHistory(nil, &b, fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " "+ss[0].Hash) || !strings.HasSuffix(lines[1], " "+ss[1].Hash) {
+		t.Errorf("unexpected history: %q", b.String())
+	}
+
+	b.Reset()
+	err = showHistory([]string{ss[0].Hash[:10]}, &b, fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := dumpSample(t)
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("unexpected snapshot; expected: %q; is: %q", expected, b.Bytes())
+	}
+
+	err = showHistory([]string{"xyz"}, &b, fl)
+	if err != history.ErrNotFound {
+		t.Errorf("unexpected error; expected: %v; is: %v", history.ErrNotFound, err)
+	}
+
+	err = showHistory(nil, &b, newFlags())
+	if err != errNoHistory {
+		t.Errorf("unexpected error; expected: %v; is: %v", errNoHistory, err)
+	}
+}
+
+func TestShowChangelog(t *testing.T) {
+	fl, dir := recordHistory(t)
+	defer os.RemoveAll(dir)
+
+	var b bytes.Buffer
+	err := showChangelog(&b, fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\n~ Kadavar: time changed from 00:10 - 01:20 to 00:30 - 01:40\n"
+	if !strings.HasSuffix(b.String(), expected) || strings.Count(b.String(), "\n") != 2 {
+		t.Errorf("unexpected changelog; expected: \"...%s\"; is: %q", expected, b.String())
+	}
+
+	err = showChangelog(&b, newFlags())
+	if err != errNoHistory {
+		t.Errorf("unexpected error; expected: %v; is: %v", errNoHistory, err)
+	}
+}
+
+func TestServeHistory(t *testing.T) {
+	fl, dir := recordHistory(t)
+	defer os.RemoveAll(dir)
+
+	ss, err := fl.store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		code    int
+		check   func(t *testing.T, data json.RawMessage)
+	}{
+		{
+			"history",
+			historyHandler(fl),
+			"/history.json",
+			http.StatusOK,
+			func(t *testing.T, data json.RawMessage) {
+				var d snapshots
+				err := json.Unmarshal(data, &d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(d.Snapshots) != 2 || d.Snapshots[1].Hash != ss[1].Hash {
+					t.Errorf("unexpected snapshots: %s", data)
+				}
+			},
+		},
+		{
+			"snapshot",
+			snapshotHandler(fl),
+			"/history/" + ss[1].Hash + ".json",
+			http.StatusOK,
+			func(t *testing.T, data json.RawMessage) {
+				if !strings.Contains(string(data), `"time":"00:30 - 01:40"`) {
+					t.Errorf("unexpected running order: %s", data)
+				}
+			},
+		},
+		{
+			"snapshot_prefix",
+			snapshotHandler(fl),
+			"/history/" + ss[0].Hash[:6] + ".json",
+			http.StatusOK,
+			func(t *testing.T, data json.RawMessage) {
+				if !strings.Contains(string(data), `"time":"00:10 - 01:20"`) {
+					t.Errorf("unexpected running order: %s", data)
+				}
+			},
+		},
+		{"snapshot_unknown", snapshotHandler(fl), "/history/xyz.json", http.StatusNotFound, nil},
+		{"snapshot_invalid", snapshotHandler(fl), "/history/" + ss[0].Hash, http.StatusNotFound, nil},
+		{
+			"changelog",
+			changelogHandler(fl),
+			"/changelog.json",
+			http.StatusOK,
+			func(t *testing.T, data json.RawMessage) {
+				var d changelog
+				err := json.Unmarshal(data, &d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(d.Changelog) != 1 || d.Changelog[0].Previous != ss[0].Hash || len(d.Changelog[0].Changes) != 1 {
+					t.Errorf("unexpected changelog: %s", data)
+				}
+			},
+		},
+	}
+
+	for _, ht := range ts {
+		t.Run(ht.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", ht.path, nil)
+			rw := httptest.NewRecorder()
+			ht.handler(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != ht.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", ht.code, r.StatusCode)
+			}
+
+			var js struct {
+				jsend
+				Data json.RawMessage `json:"data"`
+			}
+			dec := json.NewDecoder(r.Body)
+			err := dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ht.check == nil {
+				if js.Status != "error" || js.Code != ht.code {
+					t.Errorf("unexpected jsend; status: %q; code: %d", js.Status, js.Code)
+				}
+				return
+			}
+
+			ht.check(t, js.Data)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/blabber/mdjson"
+)
+
+// filter returns a copy of ro restricted to the stages and bands given by the
+// repeatable "stage" and "band" query parameters of q (see mdjson.Filter).
+func filter(ro *mdjson.RunningOrder, q url.Values) *mdjson.RunningOrder {
+	return mdjson.Filter(ro, q["stage"], q["band"])
+}
+
+// icsHandler returns a http.HandlerFunc that serves an iCalendar
+// representation of the latest running order found at URL u. The events can
+// be restricted using the "stage" and "band" query parameters (see filter).
+//
+// Errors are served as plain text, as calendar applications do not understand
+// JSend. If flags.cors is true, a wildcard Access-Control-Allow-Origin is
+// added to the response.
+func icsHandler(u string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("iCalendar request received")
+
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
+		if err != nil {
+			log.Printf("loadRunningorder: %v", err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+
+		enc := mdjson.NewICalendarEncoder(w)
+		enc.SetLocation(flags.loc)
+		err = enc.Encode(filter(ro, r.URL.Query()))
+		if err != nil {
+			log.Printf("encode: %v", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeICalendar(t *testing.T) {
+	ts := []struct {
+		query       string
+		inputData   string
+		code        int
+		contentType string
+		expected    []string
+	}{
+		{"", testdataValidHTML, http.StatusOK, "text/calendar; charset=utf-8", []string{"Amon Amarth", "Katatonia", "Kadavar", "Doro"}},
+		{"?stage=main", testdataValidHTML, http.StatusOK, "text/calendar; charset=utf-8", []string{"Amon Amarth", "Katatonia", "Doro"}},
+		{"?band=Doro&band=539", testdataValidHTML, http.StatusOK, "text/calendar; charset=utf-8", []string{"Kadavar", "Doro"}},
+		{"?stage=second&band=Doro", testdataValidHTML, http.StatusOK, "text/calendar; charset=utf-8", nil},
+		{"", testdataInvalidHTML, http.StatusInternalServerError, "text/plain; charset=utf-8", nil},
+	}
+
+	for _, it := range ts {
+		t.Run(it.query, func(t *testing.T) {
+			f, err := os.Open(it.inputData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/runningorder.ics"+it.query, nil)
+			rw := httptest.NewRecorder()
+			fl := newSampleFlags()
+			*fl.cors = true
+			icsHandler(s.URL, fl)(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != it.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", it.code, r.StatusCode)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != it.contentType {
+				t.Errorf("unexpected Content-Type; expected: %q; is: %q", it.contentType, ct)
+			}
+			if cors := r.Header.Get("Access-Control-Allow-Origin"); cors != "*" {
+				t.Errorf("unexpected Access-Control-Allow-Origin; expected: %q; is: %q", "*", cors)
+			}
+
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if it.code != http.StatusOK {
+				if !strings.HasPrefix(string(b), messagePrefixParseError) {
+					t.Errorf("unexpected error; expected: \"%s...\"; is: %q", messagePrefixParseError, b)
+				}
+				return
+			}
+
+			var is []string
+			for _, l := range strings.Split(string(b), "\r\n") {
+				if strings.HasPrefix(l, "SUMMARY:") {
+					is = append(is, strings.TrimPrefix(l, "SUMMARY:"))
+				}
+			}
+
+			if strings.Join(is, "|") != strings.Join(it.expected, "|") {
+				t.Errorf("unexpected events; expected: %q; is: %q", it.expected, is)
+			}
+			if !strings.HasPrefix(string(b), "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(string(b), "END:VCALENDAR\r\n") {
+				t.Errorf("invalid iCalendar: %q", b)
+			}
+		})
+	}
+}
+
+func TestServeICalendarLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(testdataValidHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := httptest.NewServer(dataHandler(f))
+	defer s.Close()
+
+	req := httptest.NewRequest("GET", "/runningorder.ics", nil)
+	rw := httptest.NewRecorder()
+	fl := newSampleFlags()
+	fl.loc = loc
+	icsHandler(s.URL, fl)(rw, req)
+
+	b, err := ioutil.ReadAll(rw.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"TZID:America/New_York\r\n", "DTSTART;TZID=America/New_York:20170725T223000\r\n"} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("%q missing in %q", expected, b)
+		}
+	}
+}
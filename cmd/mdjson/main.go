@@ -20,9 +20,147 @@
 //
 //	curl "http://localhost:8080/runningorder.json"
 //
+// Timestamps are given as unix seconds. Version 2 of the output schema
+// additionally provides them as RFC 3339 strings in the time zone of the
+// festival together with their duration. The version is selected using the
+// -schema flag or, for a single HTTP request, the "schema" query parameter:
+//
+//	curl "http://localhost:8080/runningorder.json?schema=2"
+//
+// By default the events are served in the order of the running order, grouped
+// by day and stage. The order of the events can be changed using the "order"
+// query parameter, which is one of "document", "start", "stage" and
+// "alphabetical". Instead of days, the "view" query parameter selects a flat
+// list of all events ("events"), the events grouped by band ("bands") or the
+// events grouped by the slot they start in ("slots"). The length of a slot
+// is given in minutes by the "slot" query parameter and defaults to 60:
+//
+//	curl "http://localhost:8080/runningorder.json?view=slots&slot=30&order=stage"
+//
+// Events on other stages overlapping with the sets of one or more bands are
+// served under the path "/clashes.json". The bands are given by their ID or
+// name using the "band" query parameter, which can be repeated:
+//
+//	curl "http://localhost:8080/clashes.json?band=Amon+Amarth&band=539"
+//
+// What is playing on every stage and what comes next is served under the path
+// "/now". The instant defaults to the current time and can be given as unix
+// seconds or RFC 3339 string using the "at" query parameter:
+//
+//	curl "http://localhost:8080/now?at=2017-07-25T21:00:00%2B02:00"
+//
+// The running order is also available as iCalendar under the path
+// "/runningorder.ics", so it can be subscribed to in calendar applications.
+// The events can be restricted to some stages, given by name or kind ("main"
+// or "second"), and bands, given by ID or name, using the repeatable "stage"
+// and "band" query parameters:
+//
+//	curl "http://localhost:8080/runningorder.ics?stage=main&band=Doro"
+//
+// For spreadsheets, the running order can be dumped as comma or tab separated
+// values, one row per event, using the -format flag. The columns are selected
+// using the -columns flag from "day", "stage", "stage_kind", "start", "end",
+// "time", "band", "id" and "url", and the format of the start and the end
+// using the -timeformat flag, which takes a Go time layout or "unix":
+//
+//	mdjson -format=csv -columns=day,start,end,band -timeformat=15:04
+//
+// The HTTP server serves these formats under the paths "/runningorder.csv" and
+// "/runningorder.tsv", taking the "columns", "timeformat", "order", "stage"
+// and "band" query parameters.
+//
+// Offline schedule apps for conferences, like Giggity, read the frab schedule
+// XML or its JSON sibling, with the stages as rooms. The running order is
+// dumped in these formats using "-format=frab-xml" and "-format=frab-json",
+// and served under the paths "/schedule.xml" and "/schedule.json", taking the
+// "stage" and "band" query parameters:
+//
+//	curl "http://localhost:8080/schedule.xml?stage=main"
+//
+// To see at a glance which sets overlap, the running order can be rendered as
+// a self-contained HTML page with a timeline per day, the stages as columns
+// and the sets as blocks proportional to their length. The page is dumped
+// using "-format=html" and served under the path "/runningorder.html", taking
+// the "stage" and "band" query parameters:
+//
+//	mdjson -format=html > runningorder.html
+//
+// If a directory is given using the -history flag, every distinct version of
+// the running order mdjson parses is kept in this directory, so it is possible
+// to find out when a band moved. The versions are identified by the hash of
+// their content. The history command lists the versions or, if a hash or a
+// unique prefix of it is given, prints the version like a dump; the changelog
+// command lists the changes between consecutive versions:
+//
+//	mdjson -history=/var/db/mdjson
+//	mdjson -history=/var/db/mdjson history
+//	mdjson -history=/var/db/mdjson history 3f2a
+//	mdjson -history=/var/db/mdjson changelog
+//
+// The HTTP server serves the list of versions under "/history.json", a
+// single version under "/history/<hash>.json" and the changelog under
+// "/changelog.json". Versions are added whenever the running order is
+// parsed, e.g. by a request to the HTTP server or by a periodic dump. The hash
+// is computed on the band names as found in the running order and leaves out
+// the stage kinds, the band IDs and the preliminary flags, so changing the
+// -names file or only these parts of the -layout file does not add a version.
+// Other changes to the layout, e.g. to the selectors of the days, stages,
+// times or announcements, may change what is found and add a version.
+//
 // You can tell the HTTP server to add a wildcard Access-Control-Allow-Origin
 // header to the replies by providing the -cors flag.
 //
+// The layout of the running order (where days, stages and events can be found
+// in the HTML) can be replaced by providing a JSON file describing it using
+// the -layout flag, e.g.
+//
+//	{"day": ".lineup_day", "event": ".band_lineup", "title": ".band_name"}
+//
+// Fields missing in the file are taken from the built-in MetalDays layout;
+// "stage_kinds" replaces the built-in stage kinds as a whole. The file is read
+// on every request, so it can be updated without restarting the server.
+//
+// Band names are title cased, e.g. "TURBOWARRIOR OF STEEL" becomes
+// "Turbowarrior Of Steel". Names that need a different spelling can be listed
+// in a file, one canonical spelling per line, provided using the -names flag:
+//
+//	# Band names
+//	AC/DC
+//	Turbowarrior of Steel
+//
+// Like the layout file, the names file is read on every request. The name as
+// found in the running order is always available as "original_label".
+//
+// The year the festival takes place in is inferred from the weekdays of the
+// days in the running order. It can be set explicitly using the -year flag;
+// if the weekdays do not match the given year, this is reported in the
+// "warnings" field of the JSend envelope.
+//
+// By default mdjson fails if any part of the running order can not be parsed.
+// If the -lenient flag is provided, the unparsable parts are skipped instead
+// and the problems are listed in the "warnings" field of the JSend envelope.
+//
+// The running order can be checked for inconsistencies, like overlapping sets
+// on the same stage or bands playing twice, using the validate command:
+//
+//	mdjson validate -year=2017
+//
+// Every issue found is printed on a line of its own, prefixed by its severity.
+// mdjson exits with a non-zero status if any issue is an error.
+//
+// Similarly, the now command prints what is playing on every stage and what
+// comes next, at the current time or at the instant given by the -at flag:
+//
+//	mdjson now -at=2017-07-25T21:00:00+02:00
+//
+// The diff command lists the changes between two running orders previously
+// dumped by mdjson, e.g. the added and removed bands and the bands that moved
+// to another time, stage or day:
+//
+//	mdjson diff old.json new.json
+//
+// If only one file is given, it is compared to the latest running order.
+//
 // [1]: http://www.metaldays.net/Line_up
 // [2]: https://labs.omniti.com/labs/jsend
 package main
@@ -35,15 +173,37 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/blabber/mdjson"
+	"github.com/blabber/mdjson/history"
 )
 
+// flags contains the values of the command line flags.
 type flags struct {
-	http *string
-	cors *bool
-	year *int
+	http    *string
+	cors    *bool
+	year    *int
+	lenient *bool
+	layout  *string
+	names   *string
+	schema  *int
+	at      *string
+	history *string
+
+	// format, columns and timeFormat select the output format of dump.
+	format     *string
+	columns    *string
+	timeFormat *string
+
+	// store is the history store opened from history. It is nil if no
+	// history is kept.
+	store *history.Store
+
+	// loc is the time zone the festival takes place in. The running order
+	// is parsed and the iCalendar is written in it.
+	loc *time.Location
 }
 
 func main() {
@@ -51,19 +211,87 @@ func main() {
 	// latest running order can be found.
 	const runningOrderURL = "http://www.metaldays.net/Line_up"
 
+	// festivalLocation is the name of the time zone the festival takes
+	// place in.
+	const festivalLocation = "Europe/Ljubljana"
+
 	var flags = flags{
-		http: flag.String("http", "", "HTTP service address"),
-		cors: flag.Bool("cors", false, "add wildcard Access-Control-Allow-Origin header to HTTP replies"),
-		year: flag.Int("year", time.Now().Year(), "the year the festival takes place"),
+		http:       flag.String("http", "", "HTTP service address"),
+		cors:       flag.Bool("cors", false, "add wildcard Access-Control-Allow-Origin header to HTTP replies"),
+		year:       flag.Int("year", 0, "the year the festival takes place (default inferred from the running order)"),
+		lenient:    flag.Bool("lenient", false, "skip unparsable parts of the running order instead of failing"),
+		layout:     flag.String("layout", "", "JSON file describing the layout of the running order"),
+		names:      flag.String("names", "", "file containing canonical spellings of band names, one per line"),
+		schema:     flag.Int("schema", mdjson.SchemaV1, "version of the output schema"),
+		format:     flag.String("format", formatJSON, "output format: json, csv, tsv, html, frab-xml or frab-json"),
+		columns:    flag.String("columns", "", "comma separated columns of the csv and tsv formats (default day,stage,start,end,band,url)"),
+		timeFormat: flag.String("timeformat", "", "Go time layout of the start and end in the csv and tsv formats, or unix (default \""+mdjson.DefaultTimeFormat+"\")"),
+		history:    flag.String("history", "", "directory keeping the history of the running order"),
+		at:         flag.String("at", "", "instant used by the now command, as unix seconds or RFC 3339 (default current time)"),
 	}
 
 	flag.Parse()
 
+	cmd := flag.Arg(0)
+	if cmd != "" {
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+
+	loc, err := time.LoadLocation(festivalLocation)
+	if err != nil {
+		log.Fatal(err)
+	}
+	flags.loc = loc
+
+	if *flags.history != "" {
+		s, err := history.Open(*flags.history)
+		if err != nil {
+			log.Fatal(err)
+		}
+		flags.store = s
+	}
+
+	switch cmd {
+	case "validate":
+		err := validate(runningOrderURL, os.Stdout, flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "now":
+		err := now(runningOrderURL, os.Stdout, flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "diff":
+		err := diff(runningOrderURL, flag.Args(), os.Stdout, flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "history":
+		err := showHistory(flag.Args(), os.Stdout, flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "changelog":
+		err := showChangelog(os.Stdout, flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "":
+	default:
+		log.Fatalf("unknown command %q", cmd)
+	}
+
 	if len(*flags.http) > 0 {
 		log.Fatal(serve(runningOrderURL, flags))
 	}
 
-	err := dump(runningOrderURL, os.Stdout, flags)
+	err = dump(runningOrderURL, os.Stdout, flags)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -91,8 +319,9 @@ type jsend struct {
 	//		for error data.
 	Status string `json:"status,omitempty"`
 
-	// Data contains the parsed running order if Status is "success".
-	Data *mdjson.RunningOrder `json:"data,omitempty"`
+	// Data contains the requested data if Status is "success", e.g. the
+	// parsed running order.
+	Data interface{} `json:"data,omitempty"`
 
 	// Message contains a human readable error message if Status is "error".
 	Message string `json:"message,omitempty"`
@@ -101,14 +330,27 @@ type jsend struct {
 	// uses this field for the HTTP status code describing the error that
 	// occured.
 	Code int `json:"code,omitempty"`
+
+	// Warnings may contain human readable descriptions of the problems
+	// encountered while parsing the running order in lenient mode, of
+	// day labels conflicting with the year and of events outside of their
+	// day. This field is not part of the JSend specification.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
-// newJsend initializes a new jsend with Status "success" and ro as Data.
+// newJsend initializes a new jsend with Status "success", ro as Data and the
+// warnings of ro as Warnings.
 func newJsend(ro *mdjson.RunningOrder) jsend {
-	return jsend{
+	j := jsend{
 		Status: "success",
 		Data:   ro,
 	}
+
+	for _, w := range ro.Warnings {
+		j.Warnings = append(j.Warnings, w.Error())
+	}
+
+	return j
 }
 
 // newJsendError initializes a new jsend with Status "error" and a string
@@ -121,11 +363,36 @@ func newJsendError(err error, code int) jsend {
 	}
 }
 
-// serve starts a HTTP server listening at address flags.http. It serves a JSON
-// representation of the latest running order, found at URL u, under path
-// "/runningorder.json".
+// serve starts a HTTP server listening at address flags.http. It serves the
+// latest running order, found at URL u, under the following paths:
+//
+//	/runningorder.json  JSON representation of the running order
+//	/runningorder.ics   iCalendar representation of the running order
+//	/runningorder.csv   comma separated values, one row per event
+//	/runningorder.tsv   tab separated values, one row per event
+//	/runningorder.html  HTML timeline of the running order
+//	/schedule.xml       frab schedule XML of the running order
+//	/schedule.json      frab schedule JSON of the running order
+//	/clashes.json       the clashes of bands
+//	/now                what is playing on every stage
+//
+// If a history is kept, the history is served under the paths
+// "/history.json", "/history/" and "/changelog.json".
 func serve(u string, flags flags) error {
 	http.Handle("/runningorder.json", runningorderHandler(u, flags))
+	http.Handle("/clashes.json", clashesHandler(u, flags))
+	http.Handle("/now", nowHandler(u, flags))
+	http.Handle("/runningorder.ics", icsHandler(u, flags))
+	http.Handle("/runningorder.csv", csvHandler(u, formatCSV, flags))
+	http.Handle("/runningorder.tsv", csvHandler(u, formatTSV, flags))
+	http.Handle("/runningorder.html", timelineHandler(u, flags))
+	http.Handle("/schedule.xml", frabHandler(u, formatFrabXML, flags))
+	http.Handle("/schedule.json", frabHandler(u, formatFrabJSON, flags))
+	if flags.store != nil {
+		http.Handle("/history.json", historyHandler(flags))
+		http.Handle("/history/", snapshotHandler(flags))
+		http.Handle("/changelog.json", changelogHandler(flags))
+	}
 
 	return http.ListenAndServe(*flags.http, nil)
 }
@@ -134,7 +401,9 @@ func serve(u string, flags flags) error {
 // representation of the latest running order found at URL u.
 //
 // If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
-// response.
+// response. The "schema" query parameter overrides flags.schema. The "view",
+// "order" and "slot" query parameters select the representation of the
+// running order (see parseView).
 func runningorderHandler(u string, flags flags) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Print("running order request received")
@@ -144,24 +413,180 @@ func runningorderHandler(u string, flags flags) http.HandlerFunc {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 		}
 
-		j, err := parseRunningOrder(u, flags)
+		// The schema of a single request must not change the
+		// schema of the following ones.
+		flags := flags
+		if s := r.URL.Query().Get("schema"); s != "" {
+			schema, err := strconv.Atoi(s)
+			if err != nil || schema < mdjson.SchemaV1 || schema > mdjson.LatestSchema {
+				err = fmt.Errorf("invalid schema version %q", s)
+				log.Printf("runningorderHandler: %v", err)
+				writeJsend(w, newJsendError(err, http.StatusBadRequest))
+				return
+			}
+			flags.schema = &schema
+		}
+
+		v, err := parseView(r.URL.Query())
+		if err != nil {
+			log.Printf("runningorderHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusBadRequest))
+			return
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
 		if err != nil {
-			log.Printf("parseRunningorder: %v", err)
-			w.WriteHeader(j.Code)
+			log.Printf("loadRunningorder: %v", err)
+			writeJsend(w, newJsendError(err, code))
+			return
+		}
+
+		j := newJsend(ro)
+		j.Data = v.data(ro)
+		writeJsend(w, j)
+	}
+}
+
+// clashes is the data returned by clashesHandler.
+type clashes struct {
+	Clashes []*mdjson.Clash `json:"clashes"`
+}
+
+// clashesHandler returns a http.HandlerFunc that serves a JSON representation
+// of the events overlapping with the bands given by the "band" query parameters
+// in the latest running order found at URL u.
+//
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
+// response.
+func clashesHandler(u string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("clashes request received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		bands := r.URL.Query()["band"]
+		if len(bands) == 0 {
+			err := fmt.Errorf("missing band")
+			log.Printf("clashesHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusBadRequest))
+			return
 		}
 
-		enc := json.NewEncoder(w)
-		err = enc.Encode(j)
+		ro, code, err := loadRunningOrder(u, flags)
 		if err != nil {
-			log.Printf("encode: %v", err)
+			log.Printf("loadRunningorder: %v", err)
+			writeJsend(w, newJsendError(err, code))
+			return
 		}
+
+		cs := mdjson.Clashes(ro, bands...)
+		if cs == nil {
+			cs = []*mdjson.Clash{}
+		}
+
+		j := newJsend(ro)
+		j.Data = clashes{cs}
+		writeJsend(w, j)
+	}
+}
+
+// nowPlaying is the data returned by nowHandler.
+type nowPlaying struct {
+	// At is the instant the status of the stages is given for, as unix
+	// seconds.
+	At int64 `json:"at"`
+
+	Stages []*mdjson.StageStatus `json:"stages"`
+}
+
+// nowHandler returns a http.HandlerFunc that serves a JSON representation of
+// what is playing on every stage of the latest running order found at URL u
+// and what comes next. The instant is given by the "at" query parameter (see
+// parseInstant).
+//
+// If flags.cors is true, a wildcard Access-Control-Allow-Origin is added to the
+// response.
+func nowHandler(u string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("now request received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		at, err := parseInstant(r.URL.Query().Get("at"))
+		if err != nil {
+			log.Printf("nowHandler: %v", err)
+			writeJsend(w, newJsendError(err, http.StatusBadRequest))
+			return
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
+		if err != nil {
+			log.Printf("loadRunningorder: %v", err)
+			writeJsend(w, newJsendError(err, code))
+			return
+		}
+
+		j := newJsend(ro)
+		j.Data = nowPlaying{at.Unix(), mdjson.NowPlaying(ro, at)}
+		writeJsend(w, j)
+	}
+}
+
+// parseInstant parses s as unix seconds or as RFC 3339 string. If s is empty,
+// the current time is returned.
+func parseInstant(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now(), nil
+	}
+
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid instant %q", s)
+	}
+
+	return t, nil
+}
+
+// writeJsend writes j to w. If j describes an error, j.Code is used as HTTP
+// status code.
+func writeJsend(w http.ResponseWriter, j jsend) {
+	if j.Status == "error" {
+		w.WriteHeader(j.Code)
+	}
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(j)
+	if err != nil {
+		log.Printf("encode: %v", err)
 	}
 }
 
 // dump parses the latest running order found at URL u and writes a JSON
 // representation to w. flags is needed for the year the festival takes place
-// in.
+// in. If flags.format selects another format, the running order is written
+// in this format instead (see dumpCSV, dumpFrab and
+// dumpTimeline).
 func dump(u string, w io.Writer, flags flags) error {
+	switch *flags.format {
+	case "", formatJSON:
+	case formatFrabXML, formatFrabJSON:
+		return dumpFrab(u, w, flags)
+	case formatHTML:
+		return dumpTimeline(u, w, flags)
+	default:
+		return dumpCSV(u, w, flags)
+	}
+
 	j, parseErr := parseRunningOrder(u, flags)
 
 	enc := json.NewEncoder(w)
@@ -177,28 +602,191 @@ func dump(u string, w io.Writer, flags flags) error {
 	return nil
 }
 
+// validate parses the latest running order found at URL u, checks it using
+// mdjson.Validate and writes the issues found to w, one per line. Warnings
+// encountered while parsing leniently are written as well. An error is
+// returned if the running order can not be parsed or if any issue is an error.
+func validate(u string, w io.Writer, flags flags) error {
+	ro, _, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return err
+	}
+
+	for _, pe := range ro.Warnings {
+		_, err = fmt.Fprintf(w, "%s: parse: %v\n", mdjson.SeverityWarning, pe)
+		if err != nil {
+			return err
+		}
+	}
+
+	issues := mdjson.Validate(ro)
+	for _, i := range issues {
+		_, err = fmt.Fprintln(w, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mdjson.HasErrors(issues) {
+		return fmt.Errorf("running order is invalid")
+	}
+
+	return nil
+}
+
+// now parses the latest running order found at URL u and writes what is
+// playing on every stage and what comes next at the instant flags.at (see
+// parseInstant) to w, one line per stage.
+func now(u string, w io.Writer, flags flags) error {
+	at, err := parseInstant(*flags.at)
+	if err != nil {
+		return err
+	}
+
+	ro, _, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range mdjson.NowPlaying(ro, at) {
+		playing := "nothing playing"
+		if s.Playing != nil {
+			playing = fmt.Sprintf("playing %s (%s)", s.Playing.Label, s.Playing.Time)
+		}
+
+		next := "nothing next"
+		if s.Next != nil {
+			next = fmt.Sprintf("next %s (%s) in %d minutes", s.Next.Label, s.Next.Time, s.MinutesUntilNext)
+		}
+
+		_, err = fmt.Fprintf(w, "%s: %s; %s\n", s.Stage, playing, next)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diff writes the changes between the running orders dumped to the files
+// names to w. names contains either the names of the old and the new running
+// order, or only the name of the old one, which is then compared to the
+// latest running order found at URL u.
+func diff(u string, names []string, w io.Writer, flags flags) error {
+	if len(names) < 1 || len(names) > 2 {
+		return fmt.Errorf("usage: mdjson diff old.json [new.json]")
+	}
+
+	old, err := readRunningOrder(names[0])
+	if err != nil {
+		return err
+	}
+
+	var cur *mdjson.RunningOrder
+	if len(names) == 2 {
+		cur, err = readRunningOrder(names[1])
+	} else {
+		cur, _, err = loadRunningOrder(u, flags)
+	}
+	if err != nil {
+		return err
+	}
+
+	return mdjson.WriteDiff(w, mdjson.Diff(old, cur))
+}
+
+// readRunningOrder reads a running order dumped by mdjson from the file name.
+func readRunningOrder(name string) (*mdjson.RunningOrder, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var j struct {
+		jsend
+		Data *mdjson.RunningOrder `json:"data"`
+	}
+	dec := json.NewDecoder(f)
+	err = dec.Decode(&j)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if j.Status != "success" || j.Data == nil {
+		return nil, fmt.Errorf("%s: no running order: %s", name, j.Message)
+	}
+
+	return j.Data, nil
+}
+
 // parseRunningOrder parses the latest running order found at URL u and returns
-// a jsend representation. flags is needed for the year the festival takes
-// place in.
+// a jsend representation. See loadRunningOrder for the meaning of flags.
 //
 // If something goes wrong the error is returned as error value and additionally
 // encoded in the JSend structure.
 func parseRunningOrder(u string, flags flags) (jsend, error) {
+	ro, code, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return newJsendError(err, code), err
+	}
+
+	return newJsend(ro), nil
+}
+
+// loadRunningOrder parses the latest running order found at URL u. flags is
+// needed for the year and the time zone the festival takes place in, whether
+// to parse leniently, the layout of the running order, the canonical spellings
+// of band names and the version of the output schema. A schema version of 0
+// selects the default schema. If flags.store is not nil, the running order is
+// added to the history; failing to do so is logged, but not returned.
+//
+// If something goes wrong the error is returned together with the HTTP status
+// code describing it.
+func loadRunningOrder(u string, flags flags) (*mdjson.RunningOrder, int, error) {
 	resp, err := http.Get(u)
 	if err != nil {
-		return newJsendError(err, http.StatusBadGateway), err
+		return nil, http.StatusBadGateway, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		err = fmt.Errorf("%s returned %q", u, resp.Status)
-		return newJsendError(err, http.StatusBadGateway), err
+		return nil, http.StatusBadGateway, err
 	}
 
-	ro, err := mdjson.ParseRunningOrder(*flags.year, resp.Body)
+	opts := []mdjson.Option{mdjson.Year(*flags.year), mdjson.Location(flags.loc), mdjson.Lenient(*flags.lenient)}
+	if *flags.schema != 0 {
+		opts = append(opts, mdjson.Schema(*flags.schema))
+	}
+	if *flags.layout != "" {
+		l, err := mdjson.LoadLayout(*flags.layout)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		opts = append(opts, mdjson.SiteLayout(l))
+	}
+	if *flags.names != "" {
+		d, err := mdjson.LoadDictionary(*flags.names)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		opts = append(opts, mdjson.LabelNormalizer(d.Normalizer(nil)))
+	}
+
+	ro, err := mdjson.ParseRunningOrderWithOptions(resp.Body, opts...)
 	if err != nil {
-		return newJsendError(err, http.StatusInternalServerError), err
+		return nil, http.StatusInternalServerError, err
 	}
 
-	return newJsend(ro), nil
+	if flags.store != nil {
+		snap, added, err := flags.store.Add(ro, time.Now())
+		if err != nil {
+			log.Printf("history: %v", err)
+		} else if added {
+			log.Printf("history: new snapshot %s", snap.Hash)
+		}
+	}
+
+	return ro, http.StatusOK, nil
 }
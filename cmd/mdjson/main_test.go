@@ -18,6 +18,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/blabber/mdjson"
 )
 
 const (
@@ -27,11 +30,62 @@ const (
 	testdataInvalidHTML = "../../testdata/fail.html"
 	testdataInvalidJSON = "../../testdata/fail.json"
 
-	messagePrefixParseError = "Unable to parse running order structure "
+	testdataInvalidLenientJSON = "../../testdata/fail_lenient.json"
+
+	testdataValidV2JSON = "../../testdata/sample_v2.json"
+
+	testdataBrokenHTML        = "../../testdata/broken.html"
+	testdataBrokenJSON        = "../../testdata/broken.json"
+	testdataBrokenLenientJSON = "../../testdata/broken_lenient.json"
+
+	messagePrefixParseError = "Unable to parse running order "
 )
 
 var year = 2018
 
+// newFlags returns flags like the command line flags would, with every flag
+// set to its zero value except year, and the time zone of the festival.
+func newFlags() flags {
+	loc, err := time.LoadLocation("Europe/Ljubljana")
+	if err != nil {
+		panic(err)
+	}
+
+	return flags{
+		http:       new(string),
+		cors:       new(bool),
+		year:       &year,
+		lenient:    new(bool),
+		layout:     new(string),
+		names:      new(string),
+		schema:     new(int),
+		at:         new(string),
+		history:    new(string),
+		format:     new(string),
+		columns:    new(string),
+		timeFormat: new(string),
+		loc:        loc,
+	}
+}
+
+// sampleYear is the year the day labels of the sample running order belong
+// to. Tests comparing with the output of the mdjson package use it instead of
+// year.
+var sampleYear = 2017
+
+// newSampleFlags returns flags like newFlags, but with year set to sampleYear.
+func newSampleFlags() flags {
+	fl := newFlags()
+	fl.year = &sampleYear
+	return fl
+}
+
+// runningOrderJsend is a jsend containing a running order as Data.
+type runningOrderJsend struct {
+	jsend
+	Data *mdjson.RunningOrder `json:"data,omitempty"`
+}
+
 func messageSuffixRemoteError(c int) string {
 	return fmt.Sprintf(" returned \"%d %s\"", c, http.StatusText(c))
 }
@@ -65,11 +119,18 @@ var dataTests = []struct {
 	expectedData string
 	validData    bool
 	cors         bool
+	lenient      bool
+	schema       int
 }{
-	{"valid_without_cors", testdataValidHTML, testdataValidJSON, true, false},
-	{"invalid_without_cors", testdataInvalidHTML, testdataInvalidJSON, false, false},
-	{"valid_with_cors", testdataValidHTML, testdataValidJSON, true, true},
-	{"invalid_with_cors", testdataInvalidHTML, testdataInvalidJSON, false, true},
+	{"valid_without_cors", testdataValidHTML, testdataValidJSON, true, false, false, 0},
+	{"invalid_without_cors", testdataInvalidHTML, testdataInvalidJSON, false, false, false, 0},
+	{"valid_with_cors", testdataValidHTML, testdataValidJSON, true, true, false, 0},
+	{"invalid_with_cors", testdataInvalidHTML, testdataInvalidJSON, false, true, false, 0},
+	{"valid_lenient", testdataValidHTML, testdataValidJSON, true, false, true, 0},
+	{"invalid_lenient", testdataInvalidHTML, testdataInvalidLenientJSON, true, false, true, 0},
+	{"broken_without_lenient", testdataBrokenHTML, testdataBrokenJSON, false, false, false, 0},
+	{"broken_lenient", testdataBrokenHTML, testdataBrokenLenientJSON, true, false, true, 0},
+	{"valid_schema_v2", testdataValidHTML, testdataValidV2JSON, true, false, false, mdjson.SchemaV2},
 }
 
 func TestDumpData(t *testing.T) {
@@ -85,7 +146,10 @@ func TestDumpData(t *testing.T) {
 			defer s.Close()
 
 			var b bytes.Buffer
-			err = dump(s.URL, &b, flags{year: &year})
+			fl := newFlags()
+			*fl.lenient = dt.lenient
+			*fl.schema = dt.schema
+			err = dump(s.URL, &b, fl)
 			if err != nil {
 				if dt.validData {
 					t.Fatal(err)
@@ -130,7 +194,11 @@ func TestServeData(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			h := runningorderHandler(s.URL, flags{cors: &dt.cors, year: &year})
+			fl := newFlags()
+			*fl.cors = dt.cors
+			*fl.lenient = dt.lenient
+			*fl.schema = dt.schema
+			h := runningorderHandler(s.URL, fl)
 			h(rw, rr)
 
 			isACAOHeader := rw.HeaderMap.Get("Access-Control-Allow-Origin")
@@ -169,6 +237,166 @@ func TestServeData(t *testing.T) {
 	}
 }
 
+func TestDumpLayout(t *testing.T) {
+	ts := []struct {
+		name      string
+		layout    string
+		validData bool
+	}{
+		{"alternative_layout", "../../testdata/alternative.layout.json", true},
+		{"invalid_layout", "../../testdata/invalid.layout.json", false},
+		{"missing_layout", "../../testdata/missing.layout.json", false},
+	}
+
+	for _, lt := range ts {
+		t.Run(lt.name, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			var b bytes.Buffer
+			fl := newFlags()
+			*fl.layout = lt.layout
+			err = dump(s.URL, &b, fl)
+			if (err == nil) != lt.validData {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			var js jsend
+			dec := json.NewDecoder(&b)
+			err = dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedStatus := "success"
+			if !lt.validData {
+				expectedStatus = "error"
+			}
+			if js.Status != expectedStatus {
+				t.Errorf("unexpected jsend.Status; expected: %q; is: %q", expectedStatus, js.Status)
+			}
+		})
+	}
+}
+
+func TestDumpNames(t *testing.T) {
+	ts := []struct {
+		name      string
+		names     string
+		validData bool
+	}{
+		{"names", "../../testdata/names.txt", true},
+		{"missing_names", "../../testdata/missing.txt", false},
+	}
+
+	for _, nt := range ts {
+		t.Run(nt.name, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			var b bytes.Buffer
+			fl := newFlags()
+			*fl.names = nt.names
+			err = dump(s.URL, &b, fl)
+			if (err == nil) != nt.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var js runningOrderJsend
+			dec := json.NewDecoder(&b)
+			err = dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !nt.validData {
+				if js.Status != "error" {
+					t.Errorf("unexpected jsend.Status; expected: %q; is: %q", "error", js.Status)
+				}
+				return
+			}
+
+			expected := "Turbowarrior of Steel"
+			is := js.Data.Days[0].Stages[0].Events[1].Label
+			if is != expected {
+				t.Errorf("unexpected label; expected: %q; is: %q", expected, is)
+			}
+		})
+	}
+}
+
+func TestServeSchema(t *testing.T) {
+	ts := []struct {
+		query     string
+		code      int
+		startTime string
+	}{
+		{"", http.StatusOK, ""},
+		{"?schema=1", http.StatusOK, ""},
+		{"?schema=2", http.StatusOK, "2017-07-26T00:10:00+02:00"},
+		{"?schema=0", http.StatusBadRequest, ""},
+		{"?schema=foo", http.StatusBadRequest, ""},
+	}
+
+	for _, st := range ts {
+		t.Run(st.query, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			rw := httptest.NewRecorder()
+			rr, err := http.NewRequest("GET", "http://example.com/runningorder.json"+st.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := runningorderHandler(s.URL, newSampleFlags())
+			h(rw, rr)
+
+			r := rw.Result()
+			if r.StatusCode != st.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", st.code, r.StatusCode)
+			}
+
+			var js runningOrderJsend
+			dec := json.NewDecoder(r.Body)
+			err = dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if st.code != http.StatusOK {
+				if js.Status != "error" || js.Code != st.code {
+					t.Errorf("unexpected jsend; status: %q; code: %d", js.Status, js.Code)
+				}
+				return
+			}
+
+			is := js.Data.Days[1].Stages[1].Events[0].TimeStamps.StartTime
+			if is != st.startTime {
+				t.Errorf("unexpected start time; expected: %q; is: %q", st.startTime, is)
+			}
+		})
+	}
+}
+
 var remoteErrorTests = []struct {
 	name string
 	code int
@@ -180,6 +408,330 @@ var remoteErrorTests = []struct {
 	{"500_without_cors", 500, false},
 }
 
+const overlapHTML = `<div class="lineup_day">
+  <div class="lineup_stage main_stage">
+    <div class="l-stage l-main">Tuesday 25. 07. <span>Main stage</span></div>
+    <div class="band_lineup" href="http://www.metaldays.net/b526/amon-amarth">
+      <span class="time">22:30 - 00:00</span> <span class="title">Amon Amarth</span>
+    </div>
+    <div class="band_lineup" href="http://www.metaldays.net/b531/katatonia">
+      <span class="time">20:45 - 22:45</span> <span class="title">Katatonia</span>
+    </div>
+  </div>
+</div>`
+
+func TestValidate(t *testing.T) {
+	ts := []struct {
+		name      string
+		input     func() (io.Reader, error)
+		lenient   bool
+		validData bool
+		expected  []string
+	}{
+		{
+			"valid",
+			func() (io.Reader, error) { return os.Open(testdataValidHTML) },
+			false,
+			true,
+			[]string{
+				"warning: missing_time: Saturday 22.07. / Newforces Stage / Tytus: ",
+				"warning: missing_time: Saturday 22.07. / Newforces Stage / Turbowarrior Of Steel: ",
+			},
+		},
+		{
+			"overlap",
+			func() (io.Reader, error) { return strings.NewReader(overlapHTML), nil },
+			false,
+			false,
+			[]string{"error: overlap: Tuesday 25.07. / Main Stage / Amon Amarth: "},
+		},
+		{
+			"broken_lenient",
+			func() (io.Reader, error) { return os.Open(testdataBrokenHTML) },
+			true,
+			true,
+			nil,
+		},
+		{
+			"broken_without_lenient",
+			func() (io.Reader, error) { return os.Open(testdataBrokenHTML) },
+			false,
+			false,
+			nil,
+		},
+	}
+
+	for _, vt := range ts {
+		t.Run(vt.name, func(t *testing.T) {
+			r, err := vt.input()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c, ok := r.(io.Closer); ok {
+				defer c.Close()
+			}
+
+			s := httptest.NewServer(dataHandler(r))
+			defer s.Close()
+
+			var b bytes.Buffer
+			fl := newSampleFlags()
+			*fl.lenient = vt.lenient
+			err = validate(s.URL, &b, fl)
+			if (err == nil) != vt.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			for i, e := range vt.expected {
+				if i >= len(lines) || !strings.HasPrefix(lines[i], e) {
+					t.Errorf("unexpected output; expected line %d to start with %q; is: %q", i, e, b.String())
+				}
+			}
+
+			if vt.lenient && !strings.HasPrefix(b.String(), "warning: parse: ") {
+				t.Errorf("parse warnings missing in output: %q", b.String())
+			}
+		})
+	}
+}
+
+const clashHTML = `<div class="lineup_day">
+  <div class="lineup_stage main_stage">
+    <div class="l-stage l-main">Tuesday 25. 07. <span>Main stage</span></div>
+    <div class="band_lineup" href="http://www.metaldays.net/b526/amon-amarth">
+      <span class="time">22:30 - 00:00</span> <span class="title">Amon Amarth</span>
+    </div>
+  </div>
+  <div class="lineup_stage second_stage">
+    <div class="l-stage l-second">Tuesday 25. 07. <span>Second stage</span></div>
+    <div class="band_lineup" href="http://www.metaldays.net/b539/kadavar">
+      <span class="time">23:40 - 01:20</span> <span class="title">Kadavar</span>
+    </div>
+  </div>
+</div>`
+
+func TestServeClashes(t *testing.T) {
+	ts := []struct {
+		name     string
+		query    string
+		code     int
+		expected []string
+	}{
+		{"label", "?band=amon+amarth", http.StatusOK, []string{"Kadavar"}},
+		{"id", "?band=539", http.StatusOK, []string{"Amon Amarth"}},
+		{"multiple", "?band=Kadavar&band=526", http.StatusOK, []string{"Amon Amarth", "Kadavar"}},
+		{"unknown", "?band=Doro", http.StatusOK, []string{}},
+		{"missing_band", "", http.StatusBadRequest, nil},
+	}
+
+	for _, ct := range ts {
+		t.Run(ct.name, func(t *testing.T) {
+			s := httptest.NewServer(dataHandler(strings.NewReader(clashHTML)))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/clashes.json"+ct.query, nil)
+			rw := httptest.NewRecorder()
+			clashesHandler(s.URL, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != ct.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", ct.code, r.StatusCode)
+			}
+
+			var js struct {
+				jsend
+				Data *clashes `json:"data,omitempty"`
+			}
+			dec := json.NewDecoder(r.Body)
+			err := dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ct.expected == nil {
+				if js.Status != "error" || js.Code != ct.code {
+					t.Errorf("unexpected jsend; status: %q; code: %d", js.Status, js.Code)
+				}
+				return
+			}
+
+			if js.Data == nil || js.Data.Clashes == nil {
+				t.Fatalf("unexpected jsend; status: %q; message: %q", js.Status, js.Message)
+			}
+			if len(js.Data.Clashes) != len(ct.expected) {
+				t.Fatalf("unexpected number of clashes; expected: %d; is: %d", len(ct.expected), len(js.Data.Clashes))
+			}
+			for i, c := range js.Data.Clashes {
+				if c.Event.Label != ct.expected[i] || c.OverlapMinutes != 20 {
+					t.Errorf("unexpected clash %d; expected: %q (20); is: %q (%d)", i, ct.expected[i], c.Event.Label, c.OverlapMinutes)
+				}
+			}
+		})
+	}
+}
+
+func TestNow(t *testing.T) {
+	ts := []struct {
+		name      string
+		at        string
+		validData bool
+		expected  string
+	}{
+		{
+			"rfc3339",
+			"2017-07-25T21:00:00+02:00",
+			true,
+			"Newforces Stage: nothing playing; nothing next\n" +
+				"Ian Fraser “Lemmy” Kilmister Stage: playing Katatonia (20:45 - 22:00); next Amon Amarth (22:30 - 00:00) in 90 minutes\n" +
+				"Boško Bursać Stage: nothing playing; next Kadavar (00:10 - 01:20) in 190 minutes\n",
+		},
+		{
+			"unix",
+			"1501021800",
+			true,
+			"Newforces Stage: nothing playing; nothing next\n" +
+				"Ian Fraser “Lemmy” Kilmister Stage: nothing playing; next Doro (22:30 - 00:00) in 1320 minutes\n" +
+				"Boško Bursać Stage: playing Kadavar (00:10 - 01:20); nothing next\n",
+		},
+		{"invalid", "tomorrow", false, ""},
+	}
+
+	for _, nt := range ts {
+		t.Run(nt.name, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			var b bytes.Buffer
+			fl := newSampleFlags()
+			*fl.at = nt.at
+			err = now(s.URL, &b, fl)
+			if (err == nil) != nt.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			is := b.String()
+			if is != nt.expected {
+				t.Errorf("now wrote unexpected data; expected: %q; is: %q", nt.expected, is)
+			}
+		})
+	}
+}
+
+func TestServeNow(t *testing.T) {
+	ts := []struct {
+		query   string
+		code    int
+		at      int64
+		playing string
+	}{
+		{"?at=2017-07-25T21:00:00%2B02:00", http.StatusOK, 1501009200, "Katatonia"},
+		{"?at=1501009200", http.StatusOK, 1501009200, "Katatonia"},
+		{"?at=1501021800", http.StatusOK, 1501021800, ""},
+		{"?at=tomorrow", http.StatusBadRequest, 0, ""},
+	}
+
+	for _, nt := range ts {
+		t.Run(nt.query, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/now"+nt.query, nil)
+			rw := httptest.NewRecorder()
+			nowHandler(s.URL, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != nt.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", nt.code, r.StatusCode)
+			}
+
+			var js struct {
+				jsend
+				Data *nowPlaying `json:"data,omitempty"`
+			}
+			dec := json.NewDecoder(r.Body)
+			err = dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if nt.code != http.StatusOK {
+				if js.Status != "error" || js.Code != nt.code {
+					t.Errorf("unexpected jsend; status: %q; code: %d", js.Status, js.Code)
+				}
+				return
+			}
+
+			if js.Data == nil || len(js.Data.Stages) != 3 {
+				t.Fatalf("unexpected jsend; status: %q; message: %q", js.Status, js.Message)
+			}
+			if js.Data.At != nt.at {
+				t.Errorf("unexpected instant; expected: %d; is: %d", nt.at, js.Data.At)
+			}
+
+			is := ""
+			if p := js.Data.Stages[1].Playing; p != nil {
+				is = p.Label
+			}
+			if is != nt.playing {
+				t.Errorf("unexpected event playing; expected: %q; is: %q", nt.playing, is)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	html, err := ioutil.ReadFile(testdataValidHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(string(html), "20:45 - 22:00", "20:30 - 21:45", 1)
+
+	ts := []struct {
+		name      string
+		names     []string
+		validData bool
+		expected  string
+	}{
+		{"files", []string{testdataValidJSON, testdataValidV2JSON}, true, "no changes\n"},
+		{"latest", []string{testdataValidJSON}, true, "~ Katatonia: time changed from 20:45 - 22:00 to 20:30 - 21:45\n"},
+		{"no_files", nil, false, ""},
+		{"too_many_files", []string{testdataValidJSON, testdataValidJSON, testdataValidJSON}, false, ""},
+		{"missing_file", []string{"../../testdata/missing.json"}, false, ""},
+		{"error_file", []string{testdataInvalidJSON, testdataValidJSON}, false, ""},
+	}
+
+	for _, dt := range ts {
+		t.Run(dt.name, func(t *testing.T) {
+			s := httptest.NewServer(dataHandler(strings.NewReader(changed)))
+			defer s.Close()
+
+			var b bytes.Buffer
+			err := diff(s.URL, dt.names, &b, newSampleFlags())
+			if (err == nil) != dt.validData {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			is := b.String()
+			if is != dt.expected {
+				t.Errorf("diff wrote unexpected data; expected: %q; is: %q", dt.expected, is)
+			}
+		})
+	}
+}
+
 func TestDumpRemoteError(t *testing.T) {
 	for _, ret := range remoteErrorTests {
 		t.Run(ret.name, func(t *testing.T) {
@@ -187,7 +739,7 @@ func TestDumpRemoteError(t *testing.T) {
 			defer s.Close()
 
 			var b bytes.Buffer
-			err := dump(s.URL, &b, flags{year: &year})
+			err := dump(s.URL, &b, newFlags())
 			if err != nil {
 				expectedSuffix := messageSuffixRemoteError(ret.code)
 				is := err.Error()
@@ -222,7 +774,9 @@ func TestServeRemoteError(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			h := runningorderHandler(s.URL, flags{cors: &ret.cors})
+			fl := newFlags()
+			*fl.cors = ret.cors
+			h := runningorderHandler(s.URL, fl)
 			h(rw, rr)
 
 			isACAOHeader := rw.HeaderMap.Get("Access-Control-Allow-Origin")
@@ -0,0 +1,59 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/blabber/mdjson"
+)
+
+// formatHTML is the format of dump writing the running order as HTML timeline.
+const formatHTML = "html"
+
+// dumpTimeline parses the latest running order found at URL u and writes it
+// to w as HTML timeline.
+func dumpTimeline(u string, w io.Writer, flags flags) error {
+	ro, _, err := loadRunningOrder(u, flags)
+	if err != nil {
+		return err
+	}
+
+	return mdjson.NewTimelineEncoder(w).Encode(ro)
+}
+
+// timelineHandler returns a http.HandlerFunc that serves the latest running
+// order found at URL u as HTML timeline. The events can be restricted using
+// the "stage" and "band" query parameters (see filter).
+//
+// Errors are served as plain text. If flags.cors is true, a wildcard
+// Access-Control-Allow-Origin is added to the response.
+func timelineHandler(u string, flags flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Print("HTML request received")
+
+		if *flags.cors {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		ro, code, err := loadRunningOrder(u, flags)
+		if err != nil {
+			log.Printf("loadRunningorder: %v", err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		err = mdjson.NewTimelineEncoder(w).Encode(filter(ro, r.URL.Query()))
+		if err != nil {
+			log.Printf("encode: %v", err)
+		}
+	}
+}
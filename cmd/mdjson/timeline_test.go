@@ -0,0 +1,104 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testdataValidTimeline = "../../testdata/sample_timeline.html"
+
+func TestDumpTimeline(t *testing.T) {
+	expected, err := ioutil.ReadFile(testdataValidTimeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(testdataValidHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := httptest.NewServer(dataHandler(f))
+	defer s.Close()
+
+	var b bytes.Buffer
+	fl := newSampleFlags()
+	*fl.format = formatHTML
+	err = dump(s.URL, &b, fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("dump wrote unexpected data; expected: %q; is: %q", expected, b.Bytes())
+	}
+}
+
+func TestServeTimeline(t *testing.T) {
+	ts := []struct {
+		name        string
+		query       string
+		inputData   string
+		code        int
+		contentType string
+		expected    []string
+		unexpected  []string
+	}{
+		{"all", "", testdataValidHTML, http.StatusOK, "text/html; charset=utf-8", []string{"Katatonia", "Kadavar", "Doro"}, nil},
+		{"band", "?band=Kadavar", testdataValidHTML, http.StatusOK, "text/html; charset=utf-8", []string{"Kadavar"}, []string{"Katatonia", "Doro"}},
+		{"invalid_data", "", testdataInvalidHTML, http.StatusInternalServerError, "text/plain; charset=utf-8", nil, nil},
+	}
+
+	for _, tt := range ts {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.inputData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/runningorder.html"+tt.query, nil)
+			rw := httptest.NewRecorder()
+			timelineHandler(s.URL, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != tt.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", tt.code, r.StatusCode)
+			}
+			if is := r.Header.Get("Content-Type"); is != tt.contentType {
+				t.Errorf("unexpected Content-Type; expected: %q; is: %q", tt.contentType, is)
+			}
+
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, e := range tt.expected {
+				if !strings.Contains(string(b), e) {
+					t.Errorf("%q missing in page", e)
+				}
+			}
+			for _, e := range tt.unexpected {
+				if strings.Contains(string(b), e) {
+					t.Errorf("unexpected %q in page", e)
+				}
+			}
+		})
+	}
+}
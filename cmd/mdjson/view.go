@@ -0,0 +1,113 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/blabber/mdjson"
+)
+
+// The views of the running order that can be requested using the "view"
+// query parameter.
+const (
+	viewDays   = "days"
+	viewEvents = "events"
+	viewBands  = "bands"
+	viewSlots  = "slots"
+)
+
+// defaultSlot is the length of a slot in the slots view if it is not given
+// using the "slot" query parameter.
+const defaultSlot = time.Hour
+
+// A view describes how the running order is presented.
+type view struct {
+	// name is one of viewDays, viewEvents, viewBands and viewSlots.
+	name string
+
+	order mdjson.Order
+
+	// slot is the length of a slot in the slots view.
+	slot time.Duration
+}
+
+// parseView parses the "view", "order" and "slot" query parameters of q. The
+// "slot" parameter gives the length of a slot in minutes.
+func parseView(q url.Values) (view, error) {
+	v := view{name: q.Get("view"), slot: defaultSlot}
+
+	switch v.name {
+	case "":
+		v.name = viewDays
+	case viewDays, viewEvents, viewBands, viewSlots:
+	default:
+		return view{}, fmt.Errorf("invalid view %q", v.name)
+	}
+
+	o, err := mdjson.ParseOrder(q.Get("order"))
+	if err != nil {
+		return view{}, fmt.Errorf("invalid order %q", q.Get("order"))
+	}
+	v.order = o
+
+	if s := q.Get("slot"); s != "" {
+		m, err := strconv.Atoi(s)
+		if err != nil || m <= 0 {
+			return view{}, fmt.Errorf("invalid slot %q", s)
+		}
+		v.slot = time.Duration(m) * time.Minute
+	}
+
+	return v, nil
+}
+
+// events is the data of the events view.
+type events struct {
+	Events []*mdjson.FlatEvent `json:"events"`
+}
+
+// bands is the data of the bands view.
+type bands struct {
+	Bands []*mdjson.BandGroup `json:"bands"`
+}
+
+// slots is the data of the slots view.
+type slots struct {
+	Slots []*mdjson.TimeSlot `json:"slots"`
+}
+
+// data returns the representation of ro according to v. The days view
+// returns ro itself, with the events of its stages reordered.
+func (v view) data(ro *mdjson.RunningOrder) interface{} {
+	switch v.name {
+	case viewEvents:
+		es := mdjson.Events(ro, v.order)
+		if es == nil {
+			es = []*mdjson.FlatEvent{}
+		}
+		return events{es}
+	case viewBands:
+		gs := mdjson.GroupByBand(ro, v.order)
+		if gs == nil {
+			gs = []*mdjson.BandGroup{}
+		}
+		return bands{gs}
+	case viewSlots:
+		ss := mdjson.GroupByTimeSlot(ro, v.slot, v.order)
+		if ss == nil {
+			ss = []*mdjson.TimeSlot{}
+		}
+		return slots{ss}
+	}
+
+	ro.Sort(v.order)
+	return ro
+}
@@ -0,0 +1,138 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blabber/mdjson"
+)
+
+func TestParseView(t *testing.T) {
+	ts := []struct {
+		query    string
+		expected view
+		valid    bool
+	}{
+		{"", view{viewDays, mdjson.OrderDocument, time.Hour}, true},
+		{"view=events&order=start", view{viewEvents, mdjson.OrderStart, time.Hour}, true},
+		{"view=slots&slot=15&order=alphabetical", view{viewSlots, mdjson.OrderAlphabetical, 15 * time.Minute}, true},
+		{"view=foo", view{}, false},
+		{"order=foo", view{}, false},
+		{"view=slots&slot=0", view{}, false},
+		{"view=slots&slot=foo", view{}, false},
+	}
+
+	for _, vt := range ts {
+		t.Run(vt.query, func(t *testing.T) {
+			q, err := url.ParseQuery(vt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := parseView(q)
+			if (err == nil) != vt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v != vt.expected {
+				t.Errorf("unexpected view; expected: %v; is: %v", vt.expected, v)
+			}
+		})
+	}
+}
+
+func TestServeView(t *testing.T) {
+	ts := []struct {
+		query    string
+		code     int
+		expected []string
+	}{
+		{"?order=start", http.StatusOK, []string{"Katatonia", "Amon Amarth"}},
+		{"?view=events&order=start", http.StatusOK, []string{"Katatonia", "Amon Amarth", "Kadavar", "Doro", "Tytus", "Turbowarrior Of Steel"}},
+		{"?view=bands&order=alphabetical", http.StatusOK, []string{"Amon Amarth", "Doro", "Kadavar", "Katatonia", "Turbowarrior Of Steel", "Tytus"}},
+		{"?view=slots&slot=120", http.StatusOK, []string{"Katatonia", "Amon Amarth", "Kadavar", "Doro"}},
+		{"?view=foo", http.StatusBadRequest, nil},
+	}
+
+	for _, vt := range ts {
+		t.Run(vt.query, func(t *testing.T) {
+			f, err := os.Open(testdataValidHTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := httptest.NewServer(dataHandler(f))
+			defer s.Close()
+
+			req := httptest.NewRequest("GET", "/runningorder.json"+vt.query, nil)
+			rw := httptest.NewRecorder()
+			runningorderHandler(s.URL, newSampleFlags())(rw, req)
+
+			r := rw.Result()
+			if r.StatusCode != vt.code {
+				t.Errorf("unexpected status; expected: %d; is: %d", vt.code, r.StatusCode)
+			}
+
+			var js struct {
+				jsend
+				Data struct {
+					Days   []*mdjson.Day       `json:"days"`
+					Events []*mdjson.FlatEvent `json:"events"`
+					Bands  []*mdjson.BandGroup `json:"bands"`
+					Slots  []*mdjson.TimeSlot  `json:"slots"`
+				} `json:"data"`
+			}
+			dec := json.NewDecoder(r.Body)
+			err = dec.Decode(&js)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if vt.expected == nil {
+				if js.Status != "error" || js.Code != vt.code {
+					t.Errorf("unexpected jsend; status: %q; code: %d", js.Status, js.Code)
+				}
+				return
+			}
+
+			var is []string
+			if js.Data.Days != nil {
+				for _, e := range js.Data.Days[1].Stages[0].Events {
+					is = append(is, e.Label)
+				}
+			}
+			for _, e := range js.Data.Events {
+				is = append(is, e.Label)
+			}
+			for _, b := range js.Data.Bands {
+				is = append(is, b.Label)
+			}
+			for _, s := range js.Data.Slots {
+				for _, e := range s.Events {
+					is = append(is, e.Label)
+				}
+			}
+
+			if len(is) != len(vt.expected) {
+				t.Fatalf("unexpected events; expected: %q; is: %q", vt.expected, is)
+			}
+			for i := range is {
+				if is[i] != vt.expected[i] {
+					t.Errorf("unexpected events; expected: %q; is: %q", vt.expected, is)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Column is a column of the rows written by a CSVEncoder.
+type Column string
+
+// The columns supported by a CSVEncoder.
+const (
+	ColumnDay       Column = "day"
+	ColumnStage     Column = "stage"
+	ColumnStageKind Column = "stage_kind"
+	ColumnStart     Column = "start"
+	ColumnEnd       Column = "end"
+	ColumnTime      Column = "time"
+	ColumnBand      Column = "band"
+	ColumnID        Column = "id"
+	ColumnURL       Column = "url"
+)
+
+// DefaultColumns returns the columns written by a CSVEncoder by default.
+func DefaultColumns() []Column {
+	return []Column{ColumnDay, ColumnStage, ColumnStart, ColumnEnd, ColumnBand, ColumnURL}
+}
+
+// ParseColumns parses a comma separated list of column names, e.g.
+// "day,start,band". An empty s selects the DefaultColumns.
+func ParseColumns(s string) ([]Column, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultColumns(), nil
+	}
+
+	var cs []Column
+	for _, n := range strings.Split(s, ",") {
+		c := Column(strings.TrimSpace(n))
+
+		switch c {
+		case ColumnDay, ColumnStage, ColumnStageKind, ColumnStart, ColumnEnd, ColumnTime, ColumnBand, ColumnID, ColumnURL:
+			cs = append(cs, c)
+		default:
+			return nil, fmt.Errorf("mdjson: unknown column %q", c)
+		}
+	}
+
+	return cs, nil
+}
+
+// TimeFormatUnix is the time format used to write timestamps as unix seconds
+// (see CSVEncoder.SetTimeFormat).
+const TimeFormatUnix = "unix"
+
+// DefaultTimeFormat is the time package layout used by a CSVEncoder by default.
+// It is understood by common spreadsheet applications.
+const DefaultTimeFormat = "2006-01-02 15:04"
+
+// A CSVEncoder writes running orders as comma or tab separated values to an
+// output stream. Every event is written as a row of its own, preceded by a
+// header row containing the names of the columns.
+type CSVEncoder struct {
+	w          io.Writer
+	comma      rune
+	columns    []Column
+	timeFormat string
+	loc        *time.Location
+	order      Order
+	header     bool
+}
+
+// NewCSVEncoder returns a new CSVEncoder that writes the DefaultColumns as
+// comma separated values to w. Times are written using DefaultTimeFormat in
+// the Europe/Ljubljana time zone, the events in the order of the running
+// order.
+func NewCSVEncoder(w io.Writer) *CSVEncoder {
+	return &CSVEncoder{w, ',', DefaultColumns(), DefaultTimeFormat, defaultLocation, OrderDocument, true}
+}
+
+// NewTSVEncoder returns a new CSVEncoder like NewCSVEncoder, but using tabs
+// to separate the values.
+func NewTSVEncoder(w io.Writer) *CSVEncoder {
+	enc := NewCSVEncoder(w)
+	enc.comma = '\t'
+
+	return enc
+}
+
+// SetColumns sets the columns written for every event.
+func (enc *CSVEncoder) SetColumns(cs ...Column) {
+	enc.columns = cs
+}
+
+// SetTimeFormat sets the time package layout used to write the start and the
+// end of events, e.g. time.RFC3339. TimeFormatUnix writes unix seconds.
+func (enc *CSVEncoder) SetTimeFormat(layout string) {
+	enc.timeFormat = layout
+}
+
+// SetLocation sets the time.Location the start and the end of events are
+// written in. A nil loc is treated as time.UTC.
+func (enc *CSVEncoder) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	enc.loc = loc
+}
+
+// SetOrder sets the order the events are written in.
+func (enc *CSVEncoder) SetOrder(by Order) {
+	enc.order = by
+}
+
+// SetHeader sets whether a header row containing the names of the columns is
+// written.
+func (enc *CSVEncoder) SetHeader(header bool) {
+	enc.header = header
+}
+
+// Encode writes the rows of all events of ro to the stream. The start and end
+// of events without timestamps are written as empty values.
+func (enc *CSVEncoder) Encode(ro *RunningOrder) error {
+	w := csv.NewWriter(enc.w)
+	w.Comma = enc.comma
+
+	if enc.header {
+		var row []string
+		for _, c := range enc.columns {
+			row = append(row, string(c))
+		}
+
+		err := w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range Events(ro, enc.order) {
+		var row []string
+		for _, c := range enc.columns {
+			row = append(row, enc.value(e, c))
+		}
+
+		err := w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// value returns the value of the column c for the event e.
+func (enc *CSVEncoder) value(e *FlatEvent, c Column) string {
+	switch c {
+	case ColumnDay:
+		return e.Day
+	case ColumnStage:
+		return e.Stage
+	case ColumnStageKind:
+		return e.StageKind
+	case ColumnStart:
+		if e.TimeStamps == nil {
+			return ""
+		}
+		return enc.formatTime(e.TimeStamps.Start)
+	case ColumnEnd:
+		if e.TimeStamps == nil || e.TimeStamps.End == 0 {
+			return ""
+		}
+		return enc.formatTime(e.TimeStamps.End)
+	case ColumnTime:
+		return e.Time
+	case ColumnBand:
+		return e.Label
+	case ColumnID:
+		return e.ID
+	case ColumnURL:
+		return e.URL
+	}
+
+	return ""
+}
+
+// formatTime formats the unix timestamp ts according to the time format of
+// enc.
+func (enc *CSVEncoder) formatTime(ts int64) string {
+	if enc.timeFormat == TimeFormatUnix {
+		return strconv.FormatInt(ts, 10)
+	}
+
+	return time.Unix(ts, 0).In(enc.loc).Format(enc.timeFormat)
+}
@@ -0,0 +1,149 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	expected, err := ioutil.ReadFile("./testdata/sample.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = NewCSVEncoder(&b).Encode(parseSample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("unexpected CSV; expected: %q; is: %q", expected, b.Bytes())
+	}
+}
+
+func TestCSVEncoderSettings(t *testing.T) {
+	ts := []struct {
+		name     string
+		setup    func(enc *CSVEncoder)
+		tsv      bool
+		expected string
+	}{
+		{
+			"tsv",
+			func(enc *CSVEncoder) {
+				enc.SetColumns(ColumnStart, ColumnEnd, ColumnBand)
+				enc.SetOrder(OrderStart)
+			},
+			true,
+			"start\tend\tband\n" +
+				"2017-07-25 20:45\t2017-07-25 22:00\tKatatonia\n" +
+				"2017-07-25 22:30\t2017-07-26 00:00\tAmon Amarth\n" +
+				"2017-07-26 00:10\t2017-07-26 01:20\tKadavar\n" +
+				"2017-07-26 22:30\t2017-07-27 00:00\tDoro\n" +
+				"\t\tTytus\n" +
+				"\t\tTurbowarrior Of Steel\n",
+		},
+		{
+			"unix",
+			func(enc *CSVEncoder) {
+				enc.SetColumns(ColumnID, ColumnStart, ColumnEnd)
+				enc.SetTimeFormat(TimeFormatUnix)
+				enc.SetHeader(false)
+				enc.SetOrder(OrderAlphabetical)
+			},
+			false,
+			"526,1501014600,1501020000\n" +
+				"529,1501101000,1501106400\n" +
+				"539,1501020600,1501024800\n" +
+				"531,1501008300,1501012800\n" +
+				"612,,\n" +
+				"613,,\n",
+		},
+		{
+			"rfc3339_utc",
+			func(enc *CSVEncoder) {
+				enc.SetColumns(ColumnBand, ColumnStageKind, ColumnTime, ColumnStart)
+				enc.SetTimeFormat(time.RFC3339)
+				enc.SetLocation(nil)
+				enc.SetHeader(false)
+				enc.SetOrder(OrderStart)
+			},
+			false,
+			"Katatonia,main,20:45 - 22:00,2017-07-25T18:45:00Z\n" +
+				"Amon Amarth,main,22:30 - 00:00,2017-07-25T20:30:00Z\n" +
+				"Kadavar,second,00:10 - 01:20,2017-07-25T22:10:00Z\n" +
+				"Doro,main,22:30 - 00:00,2017-07-26T20:30:00Z\n" +
+				"Tytus,second,-,\n" +
+				"Turbowarrior Of Steel,second,-,\n",
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			enc := NewCSVEncoder(&b)
+			if test.tsv {
+				enc = NewTSVEncoder(&b)
+			}
+			test.setup(enc)
+
+			err := enc.Encode(parseSample(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != test.expected {
+				t.Errorf("unexpected output; expected: %q; is: %q", test.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	ts := []struct {
+		s        string
+		expected []Column
+		valid    bool
+	}{
+		{"", DefaultColumns(), true},
+		{"band", []Column{ColumnBand}, true},
+		{"day, start ,band,url", []Column{ColumnDay, ColumnStart, ColumnBand, ColumnURL}, true},
+		{"day,foo", nil, false},
+		{"day,", nil, false},
+	}
+
+	for _, test := range ts {
+		t.Run(test.s, func(t *testing.T) {
+			cs, err := ParseColumns(test.s)
+			if (err == nil) != test.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cs, test.expected) {
+				t.Errorf("unexpected columns; is %q; expected %q", cs, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseColumnsError(t *testing.T) {
+	_, err := ParseColumns("day, bogus ")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expected := `mdjson: unknown column "bogus"`
+	if is := err.Error(); is != expected {
+		t.Errorf("unexpected error; is %q; expected %q", is, expected)
+	}
+}
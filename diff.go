@@ -0,0 +1,175 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A ChangeKind describes the kind of a Change.
+type ChangeKind string
+
+// The kinds of changes reported by Diff.
+const (
+	// ChangeAdded is used for an event that is only in the new running
+	// order.
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved is used for an event that is only in the old running
+	// order.
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeTime is used for an event whose time changed.
+	ChangeTime ChangeKind = "time"
+
+	// ChangeStage is used for an event that moved to another stage.
+	ChangeStage ChangeKind = "stage"
+
+	// ChangeDay is used for an event that moved to another day.
+	ChangeDay ChangeKind = "day"
+)
+
+// A Change is a difference between two running orders found by Diff.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Band is the label of the event in the new running order or, if it
+	// has been removed, in the old one.
+	Band string `json:"band"`
+
+	// Old is the event in the old running order. It is nil for
+	// ChangeAdded.
+	Old *FlatEvent `json:"old,omitempty"`
+
+	// New is the event in the new running order. It is nil for
+	// ChangeRemoved.
+	New *FlatEvent `json:"new,omitempty"`
+}
+
+// String returns a human readable, single line representation of c.
+func (c *Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Band, describeEvent(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Band, describeEvent(c.Old))
+	case ChangeTime:
+		return fmt.Sprintf("~ %s: time changed from %s to %s", c.Band, c.Old.Time, c.New.Time)
+	case ChangeStage:
+		return fmt.Sprintf("~ %s: moved from %s to %s", c.Band, c.Old.Stage, c.New.Stage)
+	case ChangeDay:
+		return fmt.Sprintf("~ %s: moved from %s to %s", c.Band, c.Old.Day, c.New.Day)
+	}
+
+	return fmt.Sprintf("? %s: %s", c.Band, c.Kind)
+}
+
+// describeEvent returns a human readable description of the day, stage and
+// time of e.
+func describeEvent(e *FlatEvent) string {
+	return fmt.Sprintf("%s, %s, %s", e.Day, e.Stage, e.Time)
+}
+
+// Diff returns the changes from the running order old to the running order
+// cur. Events are matched by their band, which is identified by its ID or, if
+// there is none, case insensitively by its label. If a band plays more than
+// once, its events are matched in the order of their start.
+//
+// A matched event can have several changes, e.g. an event moved to another day
+// usually also changes its time. Time changes are detected by comparing the
+// time as found in the running order (see Event.Time). The changes are
+// ordered by the start of the event in the new or, if it has been removed, in
+// the old running order.
+func Diff(old, cur *RunningOrder) []*Change {
+	oes := Events(old, OrderStart)
+
+	// unmatched maps the band keys to the events of the old running order
+	// that have not been matched yet.
+	unmatched := map[string][]*FlatEvent{}
+	for _, o := range oes {
+		k := bandKey(o.Event)
+		unmatched[k] = append(unmatched[k], o)
+	}
+
+	var cs []*Change
+	matched := map[*FlatEvent]bool{}
+	for _, n := range Events(cur, OrderStart) {
+		k := bandKey(n.Event)
+
+		if len(unmatched[k]) == 0 {
+			cs = append(cs, &Change{ChangeAdded, n.Label, nil, n})
+			continue
+		}
+
+		o := unmatched[k][0]
+		unmatched[k] = unmatched[k][1:]
+		matched[o] = true
+
+		cs = append(cs, compareEvents(o, n)...)
+	}
+
+	for _, o := range oes {
+		if !matched[o] {
+			cs = append(cs, &Change{ChangeRemoved, o.Label, o, nil})
+		}
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		return startsBefore(cs[i].event(), cs[j].event())
+	})
+
+	return cs
+}
+
+// event returns the event of c in the new or, if it has been removed, in the
+// old running order.
+func (c *Change) event() *Event {
+	if c.New != nil {
+		return c.New.Event
+	}
+
+	return c.Old.Event
+}
+
+// compareEvents returns the changes from the event o to the event n of the
+// same band.
+func compareEvents(o, n *FlatEvent) []*Change {
+	var cs []*Change
+
+	if o.Day != n.Day {
+		cs = append(cs, &Change{ChangeDay, n.Label, o, n})
+	}
+	if o.Stage != n.Stage {
+		cs = append(cs, &Change{ChangeStage, n.Label, o, n})
+	}
+	if strings.Join(strings.Fields(o.Time), " ") != strings.Join(strings.Fields(n.Time), " ") {
+		cs = append(cs, &Change{ChangeTime, n.Label, o, n})
+	}
+
+	return cs
+}
+
+// WriteDiff writes a human readable representation of the changes cs to w, one
+// change per line. If there are no changes, "no changes" is written.
+func WriteDiff(w io.Writer, cs []*Change) error {
+	if len(cs) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	for _, c := range cs {
+		_, err := fmt.Fprintln(w, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
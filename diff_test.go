@@ -0,0 +1,128 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	event := func(label, id, tm string, start int64) *Event {
+		return &Event{tm, &TimeStamps{Start: start}, label, label, "", id}
+	}
+	day := func(label string, stages ...*Stage) *Day {
+		return &Day{label, stages, nil, nil, false, nil}
+	}
+	stage := func(label string, events ...*Event) *Stage {
+		return &Stage{label, events, "", 1}
+	}
+
+	old := &RunningOrder{Days: []*Day{
+		day("Monday 24.07.",
+			stage("Main Stage",
+				event("Foo", "1", "20:00 - 21:00", 100),
+				event("Bar", "2", "21:00 - 22:00", 200),
+				event("Baz", "", "22:00 - 23:00", 300),
+			),
+			stage("Second Stage",
+				event("Qux", "4", "20:00 - 21:00", 100),
+				event("Twice", "5", "21:00 - 22:00", 200),
+			),
+		),
+		day("Tuesday 25.07.",
+			stage("Main Stage",
+				event("Quux", "6", "20:00 - 21:00", 1100),
+				event("Twice", "5", "21:00 - 22:00", 1200),
+			),
+		),
+	}}
+
+	cur := &RunningOrder{Days: []*Day{
+		day("Monday 24.07.",
+			stage("Main Stage",
+				event("Foo", "1", "20:00 - 21:00", 100),
+				event("Bar", "2", "21:30 - 22:30", 230),
+				event("BAZ", "", "22:00  -  23:00", 300),
+			),
+			stage("Second Stage",
+				event("Twice", "5", "21:00 - 22:00", 200),
+				event("Corge", "7", "22:00 - 23:00", 300),
+			),
+		),
+		day("Tuesday 25.07.",
+			stage("Main Stage",
+				event("Qux", "4", "20:00 - 21:00", 1100),
+			),
+			stage("Second Stage",
+				event("Twice", "5", "21:00 - 22:00", 1200),
+			),
+		),
+	}}
+
+	expected := []string{
+		"~ Bar: time changed from 21:00 - 22:00 to 21:30 - 22:30",
+		"+ Corge: Monday 24.07., Second Stage, 22:00 - 23:00",
+		"~ Qux: moved from Monday 24.07. to Tuesday 25.07.",
+		"~ Qux: moved from Second Stage to Main Stage",
+		"- Quux: Tuesday 25.07., Main Stage, 20:00 - 21:00",
+		"~ Twice: moved from Main Stage to Second Stage",
+	}
+
+	cs := Diff(old, cur)
+
+	if len(cs) != len(expected) {
+		t.Fatalf("unexpected number of changes; is %d; expected %d: %v", len(cs), len(expected), cs)
+	}
+	for i, c := range cs {
+		if c.String() != expected[i] {
+			t.Errorf("unexpected change %d; is %q; expected %q", i, c, expected[i])
+		}
+	}
+
+	if cs[0].Kind != ChangeTime || cs[0].Old.Time != "21:00 - 22:00" || cs[0].New.Time != "21:30 - 22:30" {
+		t.Errorf("unexpected change: %+v", cs[0])
+	}
+	if cs[1].Kind != ChangeAdded || cs[1].Old != nil || cs[4].Kind != ChangeRemoved || cs[4].New != nil {
+		t.Errorf("unexpected changes: %+v, %+v", cs[1], cs[4])
+	}
+
+	if cs := Diff(old, old); len(cs) != 0 {
+		t.Errorf("unexpected changes: %v", cs)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	e := &FlatEvent{Day: "Monday 24.07.", Stage: "Main Stage", Event: &Event{Time: "20:00 - 21:00", Label: "Foo"}}
+
+	ts := []struct {
+		name     string
+		cs       []*Change
+		expected string
+	}{
+		{"none", nil, "no changes\n"},
+		{
+			"changes",
+			[]*Change{{ChangeAdded, "Foo", nil, e}, {ChangeRemoved, "Foo", e, nil}},
+			"+ Foo: Monday 24.07., Main Stage, 20:00 - 21:00\n- Foo: Monday 24.07., Main Stage, 20:00 - 21:00\n",
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := WriteDiff(&b, test.cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != test.expected {
+				t.Errorf("unexpected output; is %q; expected %q", b.String(), test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ErrUnexpectedStructure is the cause wrapped by a *ParseError if the HTML
+// does not have the structure the parser expects.
+var ErrUnexpectedStructure = errors.New("unexpected running order structure")
+
+// An ErrorKind describes which part of the running order could not be parsed.
+type ErrorKind string
+
+// The kinds of errors a *ParseError can describe.
+const (
+	KindDay       ErrorKind = "day"
+	KindStage     ErrorKind = "stage"
+	KindEvent     ErrorKind = "event"
+	KindTimeStamp ErrorKind = "timestamp"
+	KindYear      ErrorKind = "year"
+
+	// KindInternal describes a bug in the parser rather than a problem
+	// with the running order.
+	KindInternal ErrorKind = "internal"
+)
+
+// maxContextLength is the maximum number of runes kept in ParseError.Context.
+const maxContextLength = 80
+
+// A ParseError describes a failure to parse a part of the running order.
+type ParseError struct {
+	// Kind describes which part of the running order could not be parsed.
+	Kind ErrorKind
+
+	// Path describes the position of the offending node in the HTML
+	// document, e.g. "html > body > div#day0.tab.lineup_day".
+	Path string
+
+	// Context contains the (shortened) text surrounding the offending
+	// node.
+	Context string
+
+	// Err is the underlying cause. Layout changes are reported as
+	// ErrUnexpectedStructure, malformed dates and times by the error
+	// returned from the time package.
+	Err error
+}
+
+// newParseError creates a new *ParseError of kind k for the node n, wrapping
+// the cause err.
+func newParseError(k ErrorKind, n *html.Node, err error) *ParseError {
+	return &ParseError{
+		Kind:    k,
+		Path:    nodePath(n),
+		Context: nodeContext(n),
+		Err:     err,
+	}
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	var b strings.Builder
+
+	switch e.Kind {
+	case KindTimeStamp:
+		b.WriteString("Unable to parse running order timestamp")
+	case KindYear:
+		b.WriteString("Unable to parse running order year")
+	case KindInternal:
+		b.WriteString("Unable to parse running order")
+	default:
+		fmt.Fprintf(&b, "Unable to parse running order structure (%s)", e.Kind)
+	}
+
+	if e.Path != "" {
+		fmt.Fprintf(&b, " at %s", e.Path)
+	}
+	if e.Context != "" {
+		fmt.Fprintf(&b, " near %q", e.Context)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+
+	return b.String()
+}
+
+// Unwrap returns the underlying cause of e.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// nodePath returns a CSS like description of the position of n in its
+// document. Every element is described by its tag name, its id and its
+// classes.
+func nodePath(n *html.Node) string {
+	var elems []string
+
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+
+		s := n.Data
+		if id := getAttributeValue(n.Attr, "id"); id != "" {
+			s += "#" + id
+		}
+		for _, c := range strings.Fields(getAttributeValue(n.Attr, "class")) {
+			s += "." + c
+		}
+
+		elems = append([]string{s}, elems...)
+	}
+
+	return strings.Join(elems, " > ")
+}
+
+// nodeContext returns the whitespace normalized text contained in n, shortened
+// to maxContextLength runes.
+func nodeContext(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	s := strings.Join(strings.Fields(nodeText(n)), " ")
+	if utf8.RuneCountInString(s) > maxContextLength {
+		s = string([]rune(s)[:maxContextLength]) + "…"
+	}
+
+	return s
+}
+
+// nodeText returns the concatenated text of all text nodes contained in n.
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+		b.WriteString(" ")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,139 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorError(t *testing.T) {
+	cause := errors.New("some cause")
+
+	ts := []struct {
+		err      *ParseError
+		expected string
+	}{
+		{
+			&ParseError{Kind: KindDay},
+			"Unable to parse running order structure (day)",
+		},
+		{
+			&ParseError{KindEvent, "html > body", "Foo", ErrUnexpectedStructure},
+			"Unable to parse running order structure (event) at html > body near \"Foo\": unexpected running order structure",
+		},
+		{
+			&ParseError{KindTimeStamp, "html > body > div.time", "", cause},
+			"Unable to parse running order timestamp at html > body > div.time: some cause",
+		},
+		{
+			&ParseError{Kind: KindInternal, Err: cause},
+			"Unable to parse running order: some cause",
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.expected, func(t *testing.T) {
+			if is := test.err.Error(); is != test.expected {
+				t.Errorf("unexpected error message; expected %q; is %q", test.expected, is)
+			}
+		})
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	var err error = &ParseError{Kind: KindStage, Err: ErrUnexpectedStructure}
+
+	if !errors.Is(err, ErrUnexpectedStructure) {
+		t.Error("errors.Is does not find wrapped cause")
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatal("errors.As does not find *ParseError")
+	}
+	if pe.Kind != KindStage {
+		t.Errorf("unexpected kind; expected %q; is %q", KindStage, pe.Kind)
+	}
+}
+
+func TestNodePath(t *testing.T) {
+	n := failRootNode.FirstChild.NextSibling.LastChild.FirstChild.NextSibling
+	expected := "html > body > div#day0.tab.lineup_day.overflow"
+
+	if is := nodePath(n); is != expected {
+		t.Errorf("unexpected path; expected %q; is %q", expected, is)
+	}
+}
+
+func TestNodeContext(t *testing.T) {
+	if is := nodeContext(nil); is != "" {
+		t.Errorf("nodeContext(nil) returns %q", is)
+	}
+
+	is := nodeContext(sampleRootNode)
+	if !strings.HasPrefix(is, "* Running order is preliminary and changes are still possible!") {
+		t.Errorf("unexpected context: %q", is)
+	}
+	if !strings.HasSuffix(is, "…") {
+		t.Errorf("context not shortened: %q", is)
+	}
+}
+
+func TestParseRunningOrderParseError(t *testing.T) {
+	f, err := os.Open("./testdata/fail.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = ParseRunningOrder(2017, f)
+	if err == nil {
+		t.Fatal("expected error did not occur")
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("error is no *ParseError: %v", err)
+	}
+	if pe.Kind != KindDay {
+		t.Errorf("unexpected kind; expected %q; is %q", KindDay, pe.Kind)
+	}
+	if !errors.Is(err, ErrUnexpectedStructure) {
+		t.Errorf("unexpected cause: %v", pe.Err)
+	}
+}
+
+func TestParseRunningOrderPanic(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	panicking := NormalizerFunc(func(string) string {
+		panic("boom")
+	})
+
+	ro, err := ParseRunningOrderWithOptions(f, Year(2017), LabelNormalizer(panicking))
+	if ro != nil {
+		t.Errorf("unexpected running order: %v", ro)
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("error is no *ParseError: %v", err)
+	}
+	if pe.Kind != KindInternal {
+		t.Errorf("unexpected kind; expected %q; is %q", KindInternal, pe.Kind)
+	}
+	if !strings.Contains(pe.Error(), "boom") {
+		t.Errorf("unexpected error message: %q", pe.Error())
+	}
+}
@@ -0,0 +1,364 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frabNamespace is the namespace of the name based UUIDs used as GUIDs in frab
+// schedules. It is the URL namespace defined by RFC 4122.
+var frabNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// frabTimeslot is the timeslot duration announced in frab schedules.
+const frabTimeslot = "00:05"
+
+// A FrabEncoder writes running orders as frab schedule, either as XML or as
+// JSON (schedule.json), to an output stream. These formats are understood by
+// many conference and festival schedule apps.
+//
+// Every day with timestamps becomes a day of the schedule, every stage a room
+// and every event with timestamps an event with the label as title and the
+// band as person. An event without an end lasts until the next event on its
+// stage or the end of its day. The GUIDs of the events are derived from the
+// ID of the band, the date of the day and the position of the set among the
+// sets of the band on that day, like the UIDs written by an ICalendarEncoder,
+// and their numeric IDs from the GUIDs.
+type FrabEncoder struct {
+	w       io.Writer
+	json    bool
+	loc     *time.Location
+	acronym string
+	title   string
+	version string
+}
+
+// NewFrabXMLEncoder returns a new FrabEncoder that writes the frab schedule
+// XML to w. The time zone defaults to Europe/Ljubljana.
+func NewFrabXMLEncoder(w io.Writer) *FrabEncoder {
+	return &FrabEncoder{w: w, loc: defaultLocation}
+}
+
+// NewFrabJSONEncoder returns a new FrabEncoder like NewFrabXMLEncoder, but
+// writing the JSON representation of the schedule (schedule.json).
+func NewFrabJSONEncoder(w io.Writer) *FrabEncoder {
+	enc := NewFrabXMLEncoder(w)
+	enc.json = true
+
+	return enc
+}
+
+// SetLocation sets the time.Location the times of the schedule are given in.
+// It should be the location the running order has been parsed with. A nil loc
+// is treated as time.UTC.
+func (enc *FrabEncoder) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	enc.loc = loc
+}
+
+// SetConference sets the acronym and the title of the conference. They
+// default to "md<year>" and "MetalDays <year>".
+func (enc *FrabEncoder) SetConference(acronym, title string) {
+	enc.acronym, enc.title = acronym, title
+}
+
+// SetVersion sets the version of the schedule. By default the version is
+// derived from the content of the running order, so it changes whenever the
+// running order changes.
+func (enc *FrabEncoder) SetVersion(version string) {
+	enc.version = version
+}
+
+// Encode writes the schedule of ro to the stream.
+func (enc *FrabEncoder) Encode(ro *RunningOrder) error {
+	s, err := enc.schedule(ro)
+	if err != nil {
+		return err
+	}
+
+	if enc.json {
+		e := json.NewEncoder(enc.w)
+		return e.Encode(struct {
+			Schedule *frabSchedule `json:"schedule"`
+		}{s})
+	}
+
+	_, err = io.WriteString(enc.w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(enc.w)
+	e.Indent("", "  ")
+	err = e.Encode(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(enc.w, "\n")
+	return err
+}
+
+// frabSchedule is the root of a frab schedule. The days are part of the
+// conference in the JSON representation, but not in the XML representation.
+type frabSchedule struct {
+	XMLName    xml.Name        `xml:"schedule" json:"-"`
+	Version    string          `xml:"version" json:"version"`
+	Conference *frabConference `xml:"conference" json:"conference"`
+	Days       []*frabDay      `xml:"day" json:"-"`
+}
+
+type frabConference struct {
+	Acronym          string     `xml:"acronym" json:"acronym"`
+	Title            string     `xml:"title" json:"title"`
+	Start            string     `xml:"start" json:"start"`
+	End              string     `xml:"end" json:"end"`
+	DaysCount        int        `xml:"days" json:"daysCount"`
+	TimeslotDuration string     `xml:"timeslot_duration" json:"timeslot_duration"`
+	TimeZoneName     string     `xml:"time_zone_name" json:"time_zone_name"`
+	Days             []*frabDay `xml:"-" json:"days"`
+}
+
+type frabDay struct {
+	Index int       `xml:"index,attr" json:"index"`
+	Date  string    `xml:"date,attr" json:"date"`
+	Start string    `xml:"start,attr" json:"day_start"`
+	End   string    `xml:"end,attr" json:"day_end"`
+	Rooms frabRooms `xml:"room" json:"rooms"`
+}
+
+// frabRooms are the rooms of a day. In the JSON representation they are an
+// object mapping the names of the rooms to their events, in the order of the
+// rooms.
+type frabRooms []*frabRoom
+
+type frabRoom struct {
+	Name   string       `xml:"name,attr"`
+	GUID   string       `xml:"guid,attr"`
+	Events []*frabEvent `xml:"event"`
+}
+
+type frabEvent struct {
+	GUID        string       `xml:"guid,attr" json:"guid"`
+	ID          int          `xml:"id,attr" json:"id"`
+	Date        string       `xml:"date" json:"date"`
+	Start       string       `xml:"start" json:"start"`
+	Duration    string       `xml:"duration" json:"duration"`
+	Room        string       `xml:"room" json:"room"`
+	Slug        string       `xml:"slug" json:"slug"`
+	URL         string       `xml:"url" json:"url"`
+	Title       string       `xml:"title" json:"title"`
+	Subtitle    string       `xml:"subtitle" json:"subtitle"`
+	Track       string       `xml:"track" json:"track"`
+	Type        string       `xml:"type" json:"type"`
+	Language    string       `xml:"language" json:"language"`
+	Abstract    string       `xml:"abstract" json:"abstract"`
+	Description string       `xml:"description" json:"description"`
+	Persons     []frabPerson `xml:"persons>person" json:"persons"`
+	Links       []frabLink   `xml:"links>link" json:"links"`
+}
+
+type frabPerson struct {
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:",chardata" json:"public_name"`
+}
+
+type frabLink struct {
+	URL   string `xml:"href,attr" json:"url"`
+	Title string `xml:",chardata" json:"title"`
+}
+
+// MarshalJSON encodes rs as JSON object mapping the names of the rooms to
+// their events, keeping the order of the rooms.
+func (rs frabRooms) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+
+	for i, r := range rs {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		k, err := json.Marshal(r.Name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.Events)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// schedule returns the frab schedule of ro.
+func (enc *FrabEncoder) schedule(ro *RunningOrder) (*frabSchedule, error) {
+	acronym, title := enc.acronym, enc.title
+	if acronym == "" {
+		acronym = fmt.Sprintf("md%d", ro.Year)
+	}
+	if title == "" {
+		title = fmt.Sprintf("MetalDays %d", ro.Year)
+	}
+
+	version := enc.version
+	if version == "" {
+		b, err := json.Marshal(ro)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		version = hex.EncodeToString(sum[:6])
+	}
+
+	c := &frabConference{
+		Acronym:          acronym,
+		Title:            title,
+		TimeslotDuration: frabTimeslot,
+		TimeZoneName:     enc.loc.String(),
+		Days:             []*frabDay{},
+	}
+
+	for _, d := range ro.Days {
+		if d.TimeStamps == nil {
+			continue
+		}
+
+		fd := enc.day(d, len(c.Days)+1, acronym)
+		if c.Start == "" {
+			c.Start = fd.Date
+		}
+		c.End = fd.Date
+		c.Days = append(c.Days, fd)
+	}
+	c.DaysCount = len(c.Days)
+
+	return &frabSchedule{Version: version, Conference: c, Days: c.Days}, nil
+}
+
+// day returns the frab day of d with the index i.
+func (enc *FrabEncoder) day(d *Day, i int, acronym string) *frabDay {
+	span := d.Span
+	if span == nil {
+		span = d.TimeStamps
+	}
+
+	fd := &frabDay{
+		Index: i,
+		Date:  time.Unix(d.TimeStamps.Start, 0).In(enc.loc).Format("2006-01-02"),
+		Start: time.Unix(span.Start, 0).In(enc.loc).Format(time.RFC3339),
+		End:   time.Unix(span.End, 0).In(enc.loc).Format(time.RFC3339),
+		Rooms: frabRooms{},
+	}
+
+	for _, s := range d.Stages {
+		r := &frabRoom{
+			Name:   s.Label,
+			GUID:   nameUUID("room:" + dictionaryKey(s.Label)),
+			Events: []*frabEvent{},
+		}
+
+		for _, e := range s.Events {
+			if e.TimeStamps == nil {
+				continue
+			}
+
+			r.Events = append(r.Events, enc.event(d, s, e, acronym))
+		}
+
+		fd.Rooms = append(fd.Rooms, r)
+	}
+
+	return fd
+}
+
+// event returns the frab event of the event e on stage s on day d.
+func (enc *FrabEncoder) event(d *Day, s *Stage, e *Event, acronym string) *frabEvent {
+	guid := nameUUID(icalUID(d, e, enc.loc))
+	id := uuidID(guid)
+
+	start := time.Unix(e.TimeStamps.Start, 0).In(enc.loc)
+
+	var duration time.Duration
+	if end := eventEnd(d, s, e); end > e.TimeStamps.Start {
+		duration = time.Duration(end-e.TimeStamps.Start) * time.Second
+	}
+
+	track := s.Kind
+	if track == "" {
+		track = s.Label
+	}
+
+	person, err := strconv.Atoi(e.ID)
+	if err != nil {
+		person = id
+	}
+
+	fe := &frabEvent{
+		GUID:     guid,
+		ID:       id,
+		Date:     start.Format(time.RFC3339),
+		Start:    start.Format("15:04"),
+		Duration: fmt.Sprintf("%02d:%02d", int(duration.Hours()), int(duration.Minutes())%60),
+		Room:     s.Label,
+		Slug:     fmt.Sprintf("%s-%d-%s", acronym, id, strings.Replace(dictionaryKey(e.Label), " ", "-", -1)),
+		URL:      e.URL,
+		Title:    e.Label,
+		Track:    track,
+		Type:     "concert",
+		Persons:  []frabPerson{{person, e.Label}},
+		Links:    []frabLink{},
+	}
+	if e.URL != "" {
+		fe.Links = append(fe.Links, frabLink{e.URL, e.Label})
+	}
+
+	return fe
+}
+
+// nameUUID returns the name based UUID (version 5) of name in frabNamespace.
+func nameUUID(name string) string {
+	h := sha1.New()
+	h.Write(frabNamespace)
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// uuidID returns a positive 31 bit integer derived from the UUID u.
+func uuidID(u string) int {
+	b, err := hex.DecodeString(strings.Replace(u, "-", "", -1)[:8])
+	if err != nil {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint32(b) & 0x7fffffff)
+}
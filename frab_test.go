@@ -0,0 +1,193 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrabEncoder(t *testing.T) {
+	ts := []struct {
+		name     string
+		newEnc   func(b *bytes.Buffer) *FrabEncoder
+		expected string
+	}{
+		{"xml", func(b *bytes.Buffer) *FrabEncoder { return NewFrabXMLEncoder(b) }, "./testdata/sample_schedule.xml"},
+		{"json", func(b *bytes.Buffer) *FrabEncoder { return NewFrabJSONEncoder(b) }, "./testdata/sample_schedule.json"},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := ioutil.ReadFile(test.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			err = test.newEnc(&b).Encode(parseSample(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b.Bytes(), expected) {
+				t.Errorf("unexpected schedule; expected: %q; is: %q", expected, b.Bytes())
+			}
+		})
+	}
+}
+
+func TestFrabEncoderSettings(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := ParseRunningOrderWithOptions(sampleReader(t), Year(2017), Location(loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	enc := NewFrabXMLEncoder(&b)
+	enc.SetLocation(loc)
+	enc.SetConference("fest17", "Some Fest 2017")
+	enc.SetVersion("1.0")
+	err = enc.Encode(ro)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s struct {
+		Version    string `xml:"version"`
+		Conference struct {
+			Acronym  string `xml:"acronym"`
+			Title    string `xml:"title"`
+			TimeZone string `xml:"time_zone_name"`
+		} `xml:"conference"`
+		Days []struct {
+			Start string `xml:"start,attr"`
+			Rooms []struct {
+				Events []struct {
+					Date string `xml:"date"`
+					Slug string `xml:"slug"`
+				} `xml:"event"`
+			} `xml:"room"`
+		} `xml:"day"`
+	}
+	err = xml.Unmarshal(b.Bytes(), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != "1.0" || s.Conference.Acronym != "fest17" || s.Conference.Title != "Some Fest 2017" || s.Conference.TimeZone != "America/New_York" {
+		t.Errorf("unexpected schedule: %+v", s)
+	}
+	if s.Days[1].Start != "2017-07-25T10:00:00-04:00" {
+		t.Errorf("unexpected start of day: %q", s.Days[1].Start)
+	}
+	e := s.Days[1].Rooms[0].Events[0]
+	if e.Date != "2017-07-25T22:30:00-04:00" || !strings.HasPrefix(e.Slug, "fest17-") || !strings.HasSuffix(e.Slug, "-amon-amarth") {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestFrabEncoderEvents(t *testing.T) {
+	ro := &RunningOrder{Year: 2017, Days: []*Day{{
+		"Saturday 22.07.",
+		[]*Stage{{"Main Stage", []*Event{
+			{"20:00 - 21:00", &TimeStamps{Start: 1500746400, End: 1500750000}, "Foo", "FOO", "", ""},
+			{"21:30", &TimeStamps{Start: 1500751800}, "Bar", "BAR", "http://example.com/bar", "42"},
+			{"22:15", &TimeStamps{Start: 1500754500}, "Baz", "BAZ", "", "43"},
+			{"TBA", nil, "Qux", "QUX", "", "44"},
+		}, "", 1}},
+		&TimeStamps{Start: 1500674400, End: 1500760800},
+		&TimeStamps{Start: 1500710400, End: 1500796800},
+		false,
+		nil,
+	}}}
+
+	var b bytes.Buffer
+	err := NewFrabJSONEncoder(&b).Encode(ro)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s struct {
+		Schedule struct {
+			Conference struct {
+				Days []struct {
+					Rooms map[string][]struct {
+						GUID     string `json:"guid"`
+						ID       int    `json:"id"`
+						Duration string `json:"duration"`
+						Track    string `json:"track"`
+						Persons  []struct {
+							ID   int    `json:"id"`
+							Name string `json:"public_name"`
+						} `json:"persons"`
+						Links []struct {
+							URL string `json:"url"`
+						} `json:"links"`
+					} `json:"rooms"`
+				} `json:"days"`
+			} `json:"conference"`
+		} `json:"schedule"`
+	}
+	err = json.Unmarshal(b.Bytes(), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es := s.Schedule.Conference.Days[0].Rooms["Main Stage"]
+	if len(es) != 3 {
+		t.Fatalf("unexpected number of events; is %d; expected %d", len(es), 3)
+	}
+
+	ts := []struct {
+		duration string
+		person   int
+		links    int
+	}{
+		{"01:00", es[0].ID, 0},
+		{"00:45", 42, 1},
+		{"11:45", 43, 0},
+	}
+
+	for i, test := range ts {
+		e := es[i]
+		if e.Duration != test.duration {
+			t.Errorf("unexpected duration of event %d; is %q; expected %q", i, e.Duration, test.duration)
+		}
+		if len(e.Persons) != 1 || e.Persons[0].ID != test.person {
+			t.Errorf("unexpected persons of event %d: %+v", i, e.Persons)
+		}
+		if len(e.Links) != test.links {
+			t.Errorf("unexpected links of event %d: %+v", i, e.Links)
+		}
+		if e.Track != "Main Stage" || e.ID <= 0 || len(e.GUID) != 36 {
+			t.Errorf("unexpected event %d: %+v", i, e)
+		}
+	}
+}
+
+func TestNameUUID(t *testing.T) {
+	// Generated using Python's uuid.uuid5(uuid.NAMESPACE_URL, ...).
+	expected := "0cfc88a7-f621-5d18-a339-506d03950a9e"
+	if is := nameUUID("526-20170725@mdjson.metaldays"); is != expected {
+		t.Errorf("unexpected UUID; is %q; expected %q", is, expected)
+	}
+
+	if is := uuidID(expected); is != 0x0cfc88a7 {
+		t.Errorf("unexpected ID; is %d; expected %d", is, 0x0cfc88a7)
+	}
+}
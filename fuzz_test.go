@@ -0,0 +1,77 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// addFuzzSeeds adds the HTML test data and some fragments with unusual time
+// strings to the seed corpus of f.
+func addFuzzSeeds(f *testing.F) {
+	for _, name := range []string{
+		"./testdata/sample.html",
+		"./testdata/fail.html",
+		"./testdata/broken.html",
+		"./testdata/alternative.html",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	for _, t := range []string{"22:30", "22:30 - ", " - ", "-", "25:00 - 26:00", "22:30 - 00:00 - 01:00"} {
+		f.Add([]byte("<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+			"<div class='band_lineup'><span class='time'>" + t + "</span><span class='title'>Doro</span></div></div></div>"))
+	}
+}
+
+// FuzzParseDocument makes sure that the parser does not panic on arbitrary
+// input. parseDocument is used instead of ParseRunningOrder as the latter
+// recovers from panics and would hide them from the fuzzer.
+func FuzzParseDocument(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		n, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		ro, err := parseDocument(n, newOptions(Year(2017)))
+		if (ro == nil) == (err == nil) {
+			t.Fatalf("strict: unexpected result; ro: %v; err: %v", ro, err)
+		}
+
+		ro, err = parseDocument(n, newOptions(Year(2017), Lenient(true)))
+		if err != nil {
+			t.Fatalf("lenient: unexpected error: %v", err)
+		}
+		if ro == nil {
+			t.Fatal("lenient: no running order returned")
+		}
+	})
+}
+
+// FuzzParseRunningOrder makes sure that ParseRunningOrder either returns a
+// RunningOrder or an error.
+func FuzzParseRunningOrder(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ro, err := ParseRunningOrder(2017, bytes.NewReader(data))
+		if (ro == nil) == (err == nil) {
+			t.Fatalf("unexpected result; ro: %v; err: %v", ro, err)
+		}
+	})
+}
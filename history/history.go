@@ -0,0 +1,398 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+// Package history stores the distinct versions of a running order parsed by
+// mdjson on the local filesystem and derives a changelog from them.
+//
+// A store is a directory containing the file "index", which lists the
+// snapshots in the order they were added, one per line as RFC 3339 time and
+// hash, and the directory "snapshots", which contains the JSON representation
+// of every distinct running order in a file named after its hash.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blabber/mdjson"
+)
+
+const (
+	// indexFile is the name of the index of a store.
+	indexFile = "index"
+
+	// snapshotDir is the name of the directory containing the snapshots
+	// of a store.
+	snapshotDir = "snapshots"
+)
+
+// ErrNotFound is returned by Store.Get if there is no snapshot matching the
+// hash.
+var ErrNotFound = errors.New("history: snapshot not found")
+
+// A Snapshot is a version of the running order.
+type Snapshot struct {
+	// Hash is the hex encoded SHA-256 hash of the canonical JSON
+	// representation of the running order (see Store.Add). It identifies
+	// the version.
+	Hash string `json:"hash"`
+
+	// Time is the time the version was added to the store.
+	Time time.Time `json:"time"`
+}
+
+// An Entry of the changelog contains the changes of a snapshot compared to its
+// predecessor.
+type Entry struct {
+	*Snapshot
+
+	// Previous is the hash of the predecessor of the snapshot.
+	Previous string `json:"previous"`
+
+	Changes []*mdjson.Change `json:"changes"`
+}
+
+// A Store stores the versions of a running order in a directory. It is safe
+// for concurrent use by multiple goroutines, but not by multiple processes.
+type Store struct {
+	dir string
+
+	// mu protects the index.
+	mu sync.Mutex
+}
+
+// Open opens the store in the directory dir. The directory is created if it
+// does not exist.
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(filepath.Join(dir, snapshotDir), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Add adds ro to the store at time at, unless it equals the latest snapshot.
+// It returns the snapshot of ro and whether it has been added.
+//
+// Running orders are stored in schema version 1 (see mdjson.SchemaV1), as the
+// fields added by later versions are derived from the timestamps.
+//
+// The hash of a running order is computed on a canonical representation in
+// schema version 1, in which the label of every event is replaced by its
+// original label, and which omits the IDs of the events, the kinds of the
+// stages and the preliminary flags. So it does not depend on the label
+// normalizer, nor on the parts of the layout deriving these fields from the
+// markup. Everything else that is found in the running order, e.g. the
+// announcements of the days, is hashed, so a layout selecting different parts
+// of the document adds a snapshot.
+func (s *Store) Add(ro *mdjson.RunningOrder, at time.Time) (*Snapshot, bool, error) {
+	b, h, err := encode(ro)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snap := &Snapshot{h, at.UTC().Truncate(time.Second)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss, err := s.list()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(ss) > 0 && ss[len(ss)-1].Hash == snap.Hash {
+		return ss[len(ss)-1], false, nil
+	}
+
+	err = writeFileAtomic(s.snapshotPath(snap.Hash), b)
+	if err != nil {
+		return nil, false, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, indexFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", snap.Time.Format(time.RFC3339), snap.Hash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return snap, true, f.Close()
+}
+
+// List returns the snapshots in the store, the oldest first. A version that
+// has been added again after a different version is listed again.
+func (s *Store) List() ([]*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list()
+}
+
+// list returns the snapshots listed in the index. s.mu has to be held.
+func (s *Store) list() ([]*Snapshot, error) {
+	name := filepath.Join(s.dir, indexFile)
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ss []*Snapshot
+
+	sc := bufio.NewScanner(f)
+	for l := 1; sc.Scan(); l++ {
+		fs := strings.Fields(sc.Text())
+		if len(fs) == 0 {
+			continue
+		}
+		if len(fs) != 2 {
+			return nil, fmt.Errorf("%s:%d: invalid entry", name, l)
+		}
+
+		t, err := time.Parse(time.RFC3339, fs[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, l, err)
+		}
+
+		ss = append(ss, &Snapshot{fs[1], t})
+	}
+
+	err = sc.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return ss, nil
+}
+
+// Get returns the running order with the hash h. h may be abbreviated to any
+// unique prefix. ErrNotFound is returned if there is no such running order.
+func (s *Store) Get(h string) (*mdjson.RunningOrder, *Snapshot, error) {
+	ss, err := s.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var snap *Snapshot
+	for _, c := range ss {
+		if h == "" || !strings.HasPrefix(c.Hash, h) {
+			continue
+		}
+		if snap != nil && snap.Hash != c.Hash {
+			return nil, nil, fmt.Errorf("history: ambiguous hash %q", h)
+		}
+		if snap == nil {
+			snap = c
+		}
+	}
+	if snap == nil {
+		return nil, nil, ErrNotFound
+	}
+
+	ro, err := s.read(snap.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ro, snap, nil
+}
+
+// Latest returns the latest running order in the store. ErrNotFound is
+// returned if the store is empty.
+func (s *Store) Latest() (*mdjson.RunningOrder, *Snapshot, error) {
+	ss, err := s.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ss) == 0 {
+		return nil, nil, ErrNotFound
+	}
+
+	snap := ss[len(ss)-1]
+	ro, err := s.read(snap.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ro, snap, nil
+}
+
+// Changelog returns the changes of every snapshot compared to its predecessor,
+// the oldest first. The first snapshot has no predecessor and is omitted.
+func (s *Store) Changelog() ([]*Entry, error) {
+	ss, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	ros := map[string]*mdjson.RunningOrder{}
+	get := func(h string) (*mdjson.RunningOrder, error) {
+		if ro, ok := ros[h]; ok {
+			return ro, nil
+		}
+
+		ro, err := s.read(h)
+		if err != nil {
+			return nil, err
+		}
+		ros[h] = ro
+
+		return ro, nil
+	}
+
+	var es []*Entry
+	for i := 1; i < len(ss); i++ {
+		old, err := get(ss[i-1].Hash)
+		if err != nil {
+			return nil, err
+		}
+		cur, err := get(ss[i].Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		cs := mdjson.Diff(old, cur)
+		if cs == nil {
+			cs = []*mdjson.Change{}
+		}
+
+		es = append(es, &Entry{ss[i], ss[i-1].Hash, cs})
+	}
+
+	return es, nil
+}
+
+// read reads the running order with the hash h.
+func (s *Store) read(h string) (*mdjson.RunningOrder, error) {
+	name := s.snapshotPath(h)
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ro mdjson.RunningOrder
+	err = json.Unmarshal(b, &ro)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return &ro, nil
+}
+
+// snapshotPath returns the name of the file containing the running order with
+// the hash h.
+func (s *Store) snapshotPath(h string) string {
+	return filepath.Join(s.dir, snapshotDir, h+".json")
+}
+
+// encode returns the JSON representation of ro in schema version 1 and the
+// hash of its canonical representation (see Store.Add).
+func encode(ro *mdjson.RunningOrder) ([]byte, string, error) {
+	b, err := json.Marshal(ro)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Work on a copy, so ro is not modified.
+	var c mdjson.RunningOrder
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.Schema = mdjson.SchemaV1
+	for _, d := range c.Days {
+		stripISOTimes(d.TimeStamps)
+		stripISOTimes(d.Span)
+
+		for _, st := range d.Stages {
+			for _, e := range st.Events {
+				stripISOTimes(e.TimeStamps)
+			}
+		}
+	}
+
+	b, err = json.Marshal(&c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The copy is not needed anymore, so it can be made canonical in
+	// place.
+	c.Preliminary = false
+	for _, d := range c.Days {
+		d.Preliminary = false
+
+		for _, st := range d.Stages {
+			st.Kind = ""
+
+			for _, e := range st.Events {
+				e.Label = e.OriginalLabel
+				e.ID = ""
+			}
+		}
+	}
+
+	cb, err := json.Marshal(&c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(cb)
+	return b, hex.EncodeToString(sum[:]), nil
+}
+
+// stripISOTimes removes the fields added by schema version 2 from ts. ts may be
+// nil.
+func stripISOTimes(ts *mdjson.TimeStamps) {
+	if ts == nil {
+		return
+	}
+
+	ts.StartTime, ts.EndTime, ts.Duration = "", "", ""
+}
+
+// writeFileAtomic writes b to the file name. The file is replaced atomically,
+// so readers never see a partially written file.
+func writeFileAtomic(name string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
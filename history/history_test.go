@@ -0,0 +1,297 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blabber/mdjson"
+)
+
+func parseSample(t *testing.T, replace ...string) *mdjson.RunningOrder {
+	b, err := os.ReadFile("../testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := strings.NewReplacer(replace...).Replace(string(b))
+
+	ro, err := mdjson.ParseRunningOrderWithOptions(strings.NewReader(html), mdjson.Year(2017))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ro
+}
+
+func openStore(t *testing.T) (*Store, string) {
+	dir, err := os.MkdirTemp("", "mdjson-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, dir
+}
+
+func TestStore(t *testing.T) {
+	s, dir := openStore(t)
+	defer os.RemoveAll(dir)
+
+	v1 := parseSample(t)
+	v2 := parseSample(t, "00:10 - 01:20", "00:30 - 01:40")
+
+	at := time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	ts := []struct {
+		ro    *mdjson.RunningOrder
+		added bool
+	}{
+		{v1, true},
+		{v1, false},
+		{v2, true},
+		{v2, false},
+		{v1, true},
+	}
+
+	var snaps []*Snapshot
+	for i, test := range ts {
+		snap, added, err := s.Add(test.ro, at.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != test.added {
+			t.Errorf("unexpected result of Add %d; is %t; expected %t", i, added, test.added)
+		}
+		if added {
+			snaps = append(snaps, snap)
+		}
+	}
+
+	if snaps[0].Hash != snaps[2].Hash || snaps[0].Hash == snaps[1].Hash {
+		t.Errorf("unexpected hashes: %q, %q, %q", snaps[0].Hash, snaps[1].Hash, snaps[2].Hash)
+	}
+
+	ss, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != len(snaps) {
+		t.Fatalf("unexpected number of snapshots; is %d; expected %d", len(ss), len(snaps))
+	}
+	for i := range ss {
+		if *ss[i] != *snaps[i] {
+			t.Errorf("unexpected snapshot %d; is %v; expected %v", i, ss[i], snaps[i])
+		}
+	}
+	if !ss[1].Time.Equal(at.Add(2 * time.Hour)) {
+		t.Errorf("unexpected time of snapshot: %v", ss[1].Time)
+	}
+
+	ro, snap, err := s.Get(snaps[1].Hash[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Hash != snaps[1].Hash || ro.Days[1].Stages[1].Events[0].Time != "00:30 - 01:40" {
+		t.Errorf("unexpected running order: %v", snap)
+	}
+
+	ro, snap, err = s.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap != nil && *snap != *snaps[2] || ro.Days[1].Stages[1].Events[0].Time != "00:10 - 01:20" {
+		t.Errorf("unexpected latest running order: %v", snap)
+	}
+
+	_, _, err = s.Get("xyz")
+	if err != ErrNotFound {
+		t.Errorf("unexpected error; is %v; expected %v", err, ErrNotFound)
+	}
+	_, _, err = s.Get("")
+	if err != ErrNotFound {
+		t.Errorf("unexpected error; is %v; expected %v", err, ErrNotFound)
+	}
+
+	// Reopening the store keeps the snapshots.
+	s, err = Open(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != len(snaps) {
+		t.Errorf("unexpected number of snapshots after reopening; is %d; expected %d", len(ss), len(snaps))
+	}
+}
+
+func TestStoreSchema(t *testing.T) {
+	s, dir := openStore(t)
+	defer os.RemoveAll(dir)
+
+	b, err := os.ReadFile("../testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v2, err := mdjson.ParseRunningOrderWithOptions(strings.NewReader(string(b)), mdjson.Year(2017), mdjson.Schema(mdjson.SchemaV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, added, err := s.Add(parseSample(t), time.Now())
+	if err != nil || !added {
+		t.Fatalf("unexpected result of Add: %t, %v", added, err)
+	}
+
+	_, added, err = s.Add(v2, time.Now())
+	if err != nil || added {
+		t.Fatalf("unexpected result of Add: %t, %v", added, err)
+	}
+
+	if v2.Schema != mdjson.SchemaV2 || v2.Days[1].TimeStamps.StartTime == "" {
+		t.Error("Add modified the running order")
+	}
+
+	ro, _, err := s.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ro.Schema != mdjson.SchemaV1 || ro.Days[1].TimeStamps.StartTime != "" {
+		t.Errorf("running order not stored in schema version 1")
+	}
+}
+
+func TestStoreOptions(t *testing.T) {
+	s, dir := openStore(t)
+	defer os.RemoveAll(dir)
+
+	b, err := os.ReadFile("../testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, added, err := s.Add(parseSample(t), time.Now())
+	if err != nil || !added {
+		t.Fatalf("unexpected result of Add: %t, %v", added, err)
+	}
+
+	l := mdjson.DefaultLayout()
+	l.StageKinds = nil
+	l.IDPattern = ""
+	l.PreliminaryPattern = ""
+
+	ts := []struct {
+		name string
+		opts []mdjson.Option
+	}{
+		{"names", []mdjson.Option{mdjson.LabelNormalizer(mdjson.NormalizerFunc(strings.ToLower))}},
+		{"layout", []mdjson.Option{mdjson.SiteLayout(l)}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]mdjson.Option{mdjson.Year(2017)}, test.opts...)
+			ro, err := mdjson.ParseRunningOrderWithOptions(strings.NewReader(string(b)), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			is, added, err := s.Add(ro, time.Now())
+			if err != nil || added {
+				t.Fatalf("unexpected result of Add: %t, %v", added, err)
+			}
+			if is.Hash != snap.Hash {
+				t.Errorf("unexpected hash; is %q; expected %q", is.Hash, snap.Hash)
+			}
+		})
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	s, dir := openStore(t)
+	defer os.RemoveAll(dir)
+
+	es, err := s.Changelog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 0 {
+		t.Errorf("unexpected changelog of empty store: %v", es)
+	}
+
+	ros := []*mdjson.RunningOrder{
+		parseSample(t),
+		parseSample(t, "00:10 - 01:20", "00:30 - 01:40"),
+		parseSample(t, "00:10 - 01:20", "00:30 - 01:40", "Doro", "Dio", "529", "530"),
+	}
+	for i, ro := range ros {
+		_, _, err := s.Add(ro, time.Date(2017, 7, 1+i, 12, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	es, err = s.Changelog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"~ Kadavar: time changed from 00:10 - 01:20 to 00:30 - 01:40"},
+		{"+ Dio: Wednesday 26.07., Ian Fraser “Lemmy” Kilmister Stage, 22:30 - 00:00",
+			"- Doro: Wednesday 26.07., Ian Fraser “Lemmy” Kilmister Stage, 22:30 - 00:00"},
+	}
+
+	if len(es) != len(expected) {
+		t.Fatalf("unexpected number of entries; is %d; expected %d", len(es), len(expected))
+	}
+	for i, e := range es {
+		if e.Hash != ss[i+1].Hash || e.Previous != ss[i].Hash {
+			t.Errorf("unexpected snapshots of entry %d: %s, %s", i, e.Hash, e.Previous)
+		}
+
+		if len(e.Changes) != len(expected[i]) {
+			t.Fatalf("unexpected changes of entry %d: %v", i, e.Changes)
+		}
+		for j, c := range e.Changes {
+			if c.String() != expected[i][j] {
+				t.Errorf("unexpected change; is %q; expected %q", c, expected[i][j])
+			}
+		}
+	}
+}
+
+func TestStoreInvalidIndex(t *testing.T) {
+	s, dir := openStore(t)
+	defer os.RemoveAll(dir)
+
+	err := os.WriteFile(filepath.Join(dir, "store", indexFile), []byte("foo\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.List()
+	if err == nil {
+		t.Error("expected error did not occur")
+	}
+}
@@ -0,0 +1,370 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// icalProdID is the product identifier of the calendars written by an
+	// ICalendarEncoder.
+	icalProdID = "-//blabber//mdjson//EN"
+
+	// icalUIDDomain is the domain part of the UIDs of the events written
+	// by an ICalendarEncoder.
+	icalUIDDomain = "mdjson.metaldays"
+
+	// icalLineLength is the maximum length of a content line in octets,
+	// excluding the line break.
+	icalLineLength = 75
+
+	// icalLocalTime is the time package layout of a local DATE-TIME.
+	icalLocalTime = "20060102T150405"
+)
+
+// An ICalendarEncoder writes running orders as iCalendar (RFC 5545) to an
+// output stream.
+//
+// Every event with timestamps becomes a VEVENT with the label of the event as
+// SUMMARY, the label of its stage as LOCATION and its URL as URL. Events
+// without an end last until the next event on their stage or the end of their
+// day. The UID is derived from the ID of the band, or its label if there is
+// none, the date of the day and the position of the set among the sets of the
+// band on that day, so it is stable across running orders as long as the band
+// plays on the same day, even if its set is moved to another time, and a band
+// playing twice on a day gets distinct UIDs. Events of preliminary days are
+// TENTATIVE. The times are
+// given in the time zone of the festival, which is described by a VTIMEZONE.
+type ICalendarEncoder struct {
+	w   io.Writer
+	loc *time.Location
+
+	// now returns the current time, used for the DTSTAMP of the events.
+	now func() time.Time
+}
+
+// NewICalendarEncoder returns a new ICalendarEncoder that writes to w. The time
+// zone defaults to Europe/Ljubljana.
+func NewICalendarEncoder(w io.Writer) *ICalendarEncoder {
+	return &ICalendarEncoder{w, defaultLocation, time.Now}
+}
+
+// SetLocation sets the time.Location the times of the events are given in. It
+// should be the location the running order has been parsed with. A nil loc is
+// treated as time.UTC.
+func (enc *ICalendarEncoder) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	enc.loc = loc
+}
+
+// Encode writes the iCalendar representation of ro to the stream.
+func (enc *ICalendarEncoder) Encode(ro *RunningOrder) error {
+	cw := &icalWriter{w: bufio.NewWriter(enc.w)}
+
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", icalProdID)
+	cw.line("CALSCALE", "GREGORIAN")
+	cw.line("METHOD", "PUBLISH")
+	if ro.Year != 0 {
+		cw.line("X-WR-CALNAME", icalText(fmt.Sprintf("Running Order %d", ro.Year)))
+	}
+	cw.line("X-WR-TIMEZONE", enc.loc.String())
+
+	first, last, ok := eventRange(ro)
+	if ok {
+		enc.writeTimeZone(cw, first, last)
+	}
+
+	stamp := enc.now().UTC().Format(icalLocalTime) + "Z"
+
+	for _, d := range ro.Days {
+		for _, s := range d.Stages {
+			for _, e := range s.Events {
+				if e.TimeStamps == nil {
+					continue
+				}
+
+				enc.writeEvent(cw, d, s, e, stamp)
+			}
+		}
+	}
+
+	cw.line("END", "VCALENDAR")
+
+	if cw.err != nil {
+		return cw.err
+	}
+
+	return cw.w.Flush()
+}
+
+// writeEvent writes the VEVENT of the event e on stage s on day d.
+func (enc *ICalendarEncoder) writeEvent(cw *icalWriter, d *Day, s *Stage, e *Event, stamp string) {
+	tzid := ";TZID=" + icalParam(enc.loc.String())
+
+	cw.line("BEGIN", "VEVENT")
+	cw.line("UID", icalText(icalUID(d, e, enc.loc)))
+	cw.line("DTSTAMP", stamp)
+	cw.line("DTSTART"+tzid, time.Unix(e.TimeStamps.Start, 0).In(enc.loc).Format(icalLocalTime))
+	if end := eventEnd(d, s, e); end > e.TimeStamps.Start {
+		cw.line("DTEND"+tzid, time.Unix(end, 0).In(enc.loc).Format(icalLocalTime))
+	}
+	cw.line("SUMMARY", icalText(e.Label))
+	cw.line("LOCATION", icalText(s.Label))
+	if e.URL != "" {
+		cw.line("URL", e.URL)
+	}
+	if d.Preliminary {
+		cw.line("STATUS", "TENTATIVE")
+	} else {
+		cw.line("STATUS", "CONFIRMED")
+	}
+	cw.line("END", "VEVENT")
+}
+
+// icalUID returns the UID of the event e on day d. It is derived from the ID of
+// the band, or its label if there is none, the date of d in loc and the
+// position of e among the events of the band on d (see icalSet).
+func icalUID(d *Day, e *Event, loc *time.Location) string {
+	ts := d.TimeStamps
+	if ts == nil {
+		ts = e.TimeStamps
+	}
+	date := time.Unix(ts.Start, 0).In(loc).Format("20060102")
+
+	return fmt.Sprintf("%s-%s-%d@%s", icalBand(e), date, icalSet(d, e), icalUIDDomain)
+}
+
+// icalSet returns the position of the event e among the events of its band on
+// day d, counted in the order of the running order starting at 1.
+func icalSet(d *Day, e *Event) int {
+	band := icalBand(e)
+
+	n := 0
+	for _, s := range d.Stages {
+		for _, f := range s.Events {
+			if icalBand(f) != band {
+				continue
+			}
+
+			n++
+			if f == e {
+				return n
+			}
+		}
+	}
+
+	return n
+}
+
+// icalBand returns the part of the UID of the event e identifying the band:
+// its ID or, if there is none, its label.
+func icalBand(e *Event) string {
+	if e.ID != "" {
+		return e.ID
+	}
+
+	return strings.Replace(dictionaryKey(e.Label), " ", "-", -1)
+}
+
+// eventRange returns the earliest start and the latest end, or start if there
+// is no end, of the events of ro. ok is false if no event has timestamps.
+func eventRange(ro *RunningOrder) (first, last int64, ok bool) {
+	for _, d := range ro.Days {
+		for _, s := range d.Stages {
+			for _, e := range s.Events {
+				if e.TimeStamps == nil {
+					continue
+				}
+
+				end := e.TimeStamps.End
+				if end == 0 {
+					end = e.TimeStamps.Start
+				}
+
+				if !ok || e.TimeStamps.Start < first {
+					first = e.TimeStamps.Start
+				}
+				if !ok || end > last {
+					last = end
+				}
+				ok = true
+			}
+		}
+	}
+
+	return first, last, ok
+}
+
+// writeTimeZone writes the VTIMEZONE of enc.loc covering the years from the
+// year of first to the year of last. Every transition is written as a
+// STANDARD or DAYLIGHT component of its own, preceded by a component for the
+// offset in effect at the beginning of the first year.
+func (enc *ICalendarEncoder) writeTimeZone(cw *icalWriter, first, last int64) {
+	start := time.Date(time.Unix(first, 0).In(enc.loc).Year(), time.January, 1, 0, 0, 0, 0, enc.loc)
+	end := time.Date(time.Unix(last, 0).In(enc.loc).Year()+1, time.January, 1, 0, 0, 0, 0, enc.loc)
+
+	ts := transitions(start, end)
+
+	cw.line("BEGIN", "VTIMEZONE")
+	cw.line("TZID", icalText(enc.loc.String()))
+
+	name, offset := start.Zone()
+	kind := "STANDARD"
+	if len(ts) > 0 {
+		_, to := ts[0].Zone()
+		if to < offset {
+			kind = "DAYLIGHT"
+		}
+	}
+	writeObservance(cw, kind, start.Format(icalLocalTime), offset, offset, name)
+
+	for _, t := range ts {
+		name, to := t.Zone()
+
+		kind := "STANDARD"
+		if to > offset {
+			kind = "DAYLIGHT"
+		}
+
+		// The onset is given in the local time before the transition.
+		onset := t.In(time.FixedZone("", offset)).Format(icalLocalTime)
+		writeObservance(cw, kind, onset, offset, to, name)
+
+		offset = to
+	}
+
+	cw.line("END", "VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT component of a VTIMEZONE.
+func writeObservance(cw *icalWriter, kind, onset string, from, to int, name string) {
+	cw.line("BEGIN", kind)
+	cw.line("DTSTART", onset)
+	cw.line("TZOFFSETFROM", icalOffset(from))
+	cw.line("TZOFFSETTO", icalOffset(to))
+	if name != "" {
+		cw.line("TZNAME", icalText(name))
+	}
+	cw.line("END", kind)
+}
+
+// transitions returns the instants in [start, end) at which the UTC offset of
+// the location of start changes.
+func transitions(start, end time.Time) []time.Time {
+	var ts []time.Time
+
+	for t := start; t.Before(end); {
+		next := t.Add(24 * time.Hour)
+		if next.After(end) {
+			next = end
+		}
+
+		_, o1 := t.Zone()
+		_, o2 := next.Zone()
+		if o1 != o2 {
+			// Find the first second with the new offset.
+			lo, hi := t.Unix(), next.Unix()
+			for hi-lo > 1 {
+				mid := lo + (hi-lo)/2
+				if _, o := time.Unix(mid, 0).In(start.Location()).Zone(); o == o1 {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			ts = append(ts, time.Unix(hi, 0).In(start.Location()))
+		}
+
+		t = next
+	}
+
+	return ts
+}
+
+// icalOffset formats the UTC offset o, given in seconds, as iCalendar
+// UTC-OFFSET, e.g. "+0200".
+func icalOffset(o int) string {
+	sign := "+"
+	if o < 0 {
+		sign = "-"
+		o = -o
+	}
+
+	s := fmt.Sprintf("%s%02d%02d", sign, o/3600, o%3600/60)
+	if o%60 != 0 {
+		s += fmt.Sprintf("%02d", o%60)
+	}
+
+	return s
+}
+
+// icalText escapes s as iCalendar TEXT value.
+func icalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// icalParam quotes s as iCalendar parameter value if necessary.
+func icalParam(s string) string {
+	s = strings.Replace(s, `"`, "", -1)
+	if strings.ContainsAny(s, ";:,") {
+		return `"` + s + `"`
+	}
+
+	return s
+}
+
+// An icalWriter writes folded iCalendar content lines. The first error is
+// kept in err and stops all further writes.
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes the content line consisting of name and value. Lines longer than
+// icalLineLength octets are folded without splitting UTF-8 sequences.
+func (cw *icalWriter) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+
+	l := name + ":" + value
+
+	var b strings.Builder
+	n := 0
+	for len(l) > 0 {
+		_, size := utf8.DecodeRuneInString(l)
+		if n+size > icalLineLength {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+
+		b.WriteString(l[:size])
+		n += size
+		l = l[size:]
+	}
+	b.WriteString("\r\n")
+
+	_, cw.err = cw.w.WriteString(b.String())
+}
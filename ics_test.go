@@ -0,0 +1,238 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalendarEncoder(t *testing.T) {
+	ro := parseSample(t)
+
+	var b bytes.Buffer
+	enc := NewICalendarEncoder(&b)
+	enc.now = func() time.Time {
+		return time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	err := enc.Encode(ro)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ioutil.ReadFile("./testdata/sample.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("unexpected iCalendar; expected: %q; is: %q", expected, b.Bytes())
+	}
+}
+
+func TestICalendarEncoderTimeZones(t *testing.T) {
+	ts := []struct {
+		name     string
+		expected []string
+	}{
+		{"UTC", []string{"BEGIN:STANDARD", "DTSTART:20170101T000000", "TZOFFSETFROM:+0000", "TZOFFSETTO:+0000", "TZNAME:UTC", "END:STANDARD"}},
+		{"Australia/Sydney", []string{
+			"BEGIN:DAYLIGHT", "DTSTART:20170101T000000", "TZOFFSETFROM:+1100", "TZOFFSETTO:+1100", "TZNAME:AEDT", "END:DAYLIGHT",
+			"BEGIN:STANDARD", "DTSTART:20170402T030000", "TZOFFSETFROM:+1100", "TZOFFSETTO:+1000", "TZNAME:AEST", "END:STANDARD",
+			"BEGIN:DAYLIGHT", "DTSTART:20171001T020000", "TZOFFSETFROM:+1000", "TZOFFSETTO:+1100", "TZNAME:AEDT", "END:DAYLIGHT",
+		}},
+		{"Asia/Kolkata", []string{"BEGIN:STANDARD", "DTSTART:20170101T000000", "TZOFFSETFROM:+0530", "TZOFFSETTO:+0530", "TZNAME:IST", "END:STANDARD"}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(test.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := ParseRunningOrderWithOptions(sampleReader(t), Year(2017), Location(loc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			enc := NewICalendarEncoder(&b)
+			enc.SetLocation(loc)
+			err = enc.Encode(ro)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ical := b.String()
+			start := strings.Index(ical, "TZID:"+test.name+"\r\n")
+			end := strings.Index(ical, "END:VTIMEZONE\r\n")
+			if start < 0 || end < start {
+				t.Fatalf("VTIMEZONE missing: %q", ical)
+			}
+
+			is := strings.Split(strings.TrimSuffix(ical[start:end], "\r\n"), "\r\n")[1:]
+			if strings.Join(is, "|") != strings.Join(test.expected, "|") {
+				t.Errorf("unexpected VTIMEZONE; expected: %q; is: %q", test.expected, is)
+			}
+
+			expected := "DTSTART;TZID=" + test.name + ":" + time.Unix(ro.Days[1].Stages[0].Events[0].TimeStamps.Start, 0).In(loc).Format(icalLocalTime)
+			if !strings.Contains(ical, expected) {
+				t.Errorf("%q missing", expected)
+			}
+		})
+	}
+}
+
+func sampleReader(t *testing.T) *bytes.Reader {
+	b, err := ioutil.ReadFile("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(b)
+}
+
+func TestICalendarEncoderEmpty(t *testing.T) {
+	var b bytes.Buffer
+	err := NewICalendarEncoder(&b).Encode(&RunningOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(b.String(), "VTIMEZONE") || strings.Contains(b.String(), "VEVENT") {
+		t.Errorf("unexpected iCalendar: %q", b.String())
+	}
+}
+
+func TestICalendarEncoderOpenEnd(t *testing.T) {
+	r := strings.NewReader("<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+		"<div class='band_lineup'><span class='time'>20:00</span><span class='title'>Doro</span></div>" +
+		"<div class='band_lineup'><span class='time'>22:30 -</span><span class='title'>Amon Amarth</span></div></div></div>")
+
+	ro, err := ParseRunningOrderWithOptions(r, Year(2017))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = NewICalendarEncoder(&b).Encode(ro)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var is []string
+	for _, l := range strings.Split(b.String(), "\r\n") {
+		if strings.HasPrefix(l, "DTEND") {
+			is = append(is, l)
+		}
+	}
+
+	expected := []string{
+		"DTEND;TZID=Europe/Ljubljana:20170725T223000",
+		"DTEND;TZID=Europe/Ljubljana:20170726T100000",
+	}
+	if strings.Join(is, "|") != strings.Join(expected, "|") {
+		t.Errorf("unexpected ends; expected: %q; is: %q", expected, is)
+	}
+}
+
+func TestICalUID(t *testing.T) {
+	first := &Event{TimeStamps: &TimeStamps{Start: 1500793200}, Label: "Amon Amarth", ID: "526"}
+	second := &Event{TimeStamps: &TimeStamps{Start: 1500825600}, Label: "Amon Amarth", ID: "526"}
+	noID := &Event{TimeStamps: &TimeStamps{Start: 1500825600}, Label: "Turbowarrior Of Steel"}
+	d := &Day{
+		TimeStamps: &TimeStamps{Start: 1500760800, End: 1500847200},
+		Stages: []*Stage{
+			{Label: "Main", Events: []*Event{first, noID}},
+			{Label: "Second", Events: []*Event{second}},
+		},
+	}
+
+	ts := []struct {
+		e        *Event
+		expected string
+	}{
+		{first, "526-20170723-1@mdjson.metaldays"},
+		{second, "526-20170723-2@mdjson.metaldays"},
+		{noID, "turbowarrior-of-steel-20170723-1@mdjson.metaldays"},
+	}
+
+	for _, test := range ts {
+		if is := icalUID(d, test.e, defaultLocation); is != test.expected {
+			t.Errorf("unexpected UID; is %q; expected %q", is, test.expected)
+		}
+	}
+
+	// Moving a set keeps its UID.
+	first.TimeStamps.Start = 1500829200
+	if is := icalUID(d, first, defaultLocation); is != "526-20170723-1@mdjson.metaldays" {
+		t.Errorf("UID of a moved set changed to %q", is)
+	}
+}
+
+func TestICalendarLines(t *testing.T) {
+	ts := []struct {
+		name, value string
+		expected    string
+	}{
+		{"SUMMARY", "Amon Amarth", "SUMMARY:Amon Amarth\r\n"},
+		{"SUMMARY", icalText("A, B; C\\D\nE"), `SUMMARY:A\, B\; C\\D\nE` + "\r\n"},
+		{
+			"SUMMARY",
+			strings.Repeat("x", 80),
+			"SUMMARY:" + strings.Repeat("x", 67) + "\r\n " + strings.Repeat("x", 13) + "\r\n",
+		},
+		{
+			"LOCATION",
+			strings.Repeat("ć", 40),
+			"LOCATION:" + strings.Repeat("ć", 33) + "\r\n " + strings.Repeat("ć", 7) + "\r\n",
+		},
+	}
+
+	for _, test := range ts {
+		var b bytes.Buffer
+		cw := &icalWriter{w: bufio.NewWriter(&b)}
+		cw.line(test.name, test.value)
+		cw.w.Flush()
+
+		if b.String() != test.expected {
+			t.Errorf("unexpected line; expected: %q; is: %q", test.expected, b.String())
+		}
+
+		for _, l := range strings.Split(b.String(), "\r\n") {
+			if len(l) > icalLineLength {
+				t.Errorf("line too long: %q", l)
+			}
+		}
+	}
+}
+
+func TestICalOffset(t *testing.T) {
+	ts := []struct {
+		o        int
+		expected string
+	}{
+		{0, "+0000"},
+		{7200, "+0200"},
+		{19800, "+0530"},
+		{-16200, "-0430"},
+		{-36000, "-1000"},
+		{3723, "+010203"},
+	}
+
+	for _, test := range ts {
+		if is := icalOffset(test.o); is != test.expected {
+			t.Errorf("unexpected offset of %d; expected: %q; is: %q", test.o, test.expected, is)
+		}
+	}
+}
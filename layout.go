@@ -0,0 +1,280 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// A Layout describes where the parts of the running order can be found in the
+// HTML document. All fields except LinkAttribute, IDAttribute, IDPattern,
+// StageKinds, PreliminaryPattern and CopyrightPattern contain CSS selectors
+// (see below for the supported subset).
+//
+// Days are searched in the whole document, stages below the element of their
+// day and events below the element of their stage. All other selectors are
+// evaluated below the element of the day, stage or event they belong to. The
+// text of an element is the text of its direct text children or, if there is
+// none, the text of all its descendants.
+//
+// The supported selectors are type selectors, the universal selector, id and
+// class selectors, the attribute selectors [attr], [attr=value] and
+// [attr~=value], the descendant and child combinators and comma separated
+// selector groups.
+type Layout struct {
+	// Day selects the elements containing the days.
+	Day string `json:"day"`
+
+	// DayLabel selects the element containing the date of a day.
+	DayLabel string `json:"day_label"`
+
+	// Notice selects the elements containing announcements for a day. Line
+	// breaks and block elements separate the announcements of a single
+	// notice. If Notice is empty, no announcements are extracted.
+	Notice string `json:"notice"`
+
+	// PreliminaryPattern is a regular expression. A day is marked as
+	// preliminary if any of its announcements matches it. If
+	// PreliminaryPattern is empty, no day is marked as preliminary.
+	PreliminaryPattern string `json:"preliminary_pattern"`
+
+	// Stage selects the elements containing the stages of a day.
+	Stage string `json:"stage"`
+
+	// StageName selects the element containing the name of a stage.
+	StageName string `json:"stage_name"`
+
+	// StageKinds maps CSS classes to the normalized kind of a stage (see
+	// Stage.Kind). The kind is taken from the first class found on the
+	// element of the stage or, if there is none, on its descendants.
+	StageKinds map[string]string `json:"stage_kinds"`
+
+	// Event selects the elements containing the events of a stage.
+	Event string `json:"event"`
+
+	// Time selects the element containing the time of an event.
+	Time string `json:"time"`
+
+	// Title selects the element containing the name of an event.
+	Title string `json:"title"`
+
+	// Link selects the element carrying the link to additional information
+	// about an event. If Link is empty, the element of the event itself is
+	// used.
+	Link string `json:"link"`
+
+	// LinkAttribute is the attribute of the Link element containing the
+	// URL.
+	LinkAttribute string `json:"link_attribute"`
+
+	// ID selects the element carrying the ID of an event. If ID is empty
+	// or there is no such element, the ID is extracted from the URL using
+	// IDPattern.
+	ID string `json:"id"`
+
+	// IDAttribute is the attribute of the ID element containing the ID.
+	IDAttribute string `json:"id_attribute"`
+
+	// IDPattern is a regular expression that is applied to the URL of an
+	// event if its ID can not be found using ID. The first submatch is
+	// used as the ID. If IDPattern is empty, no ID is extracted from the
+	// URL.
+	IDPattern string `json:"id_pattern"`
+
+	// CopyrightPattern is a regular expression that is applied to the text
+	// of the whole document to find the copyright year, which is used to
+	// infer the year of the running order. The first submatch is used as
+	// the year. If CopyrightPattern is empty, the copyright year is not
+	// used.
+	CopyrightPattern string `json:"copyright_pattern"`
+}
+
+// DefaultLayout returns the Layout of the MetalDays website.
+func DefaultLayout() *Layout {
+	return &Layout{
+		Day:                ".lineup_day",
+		DayLabel:           ".l-stage",
+		Notice:             ".order_info",
+		PreliminaryPattern: `(?i)\bpreliminary\b`,
+		Stage:              ".lineup_stage",
+		StageName:          ".l-stage > span",
+		StageKinds: map[string]string{
+			"main_stage":   StageKindMain,
+			"l-main":       StageKindMain,
+			"second_stage": StageKindSecond,
+			"l-second":     StageKindSecond,
+		},
+		Event:            ".band_lineup",
+		Time:             ".time",
+		Title:            ".title",
+		Link:             "",
+		LinkAttribute:    "href",
+		ID:               "input.band-selection",
+		IDAttribute:      "value",
+		IDPattern:        `/b([0-9]+)/`,
+		CopyrightPattern: `(?i)(?:©|\(c\)|copyright)\s*(?:[0-9]{4}\s*[-–]\s*)?([0-9]{4})`,
+	}
+}
+
+// LoadLayout reads a JSON encoded Layout from the file name. Fields missing in
+// the file are taken from DefaultLayout. StageKinds given in the file replace
+// the ones of DefaultLayout instead of being merged with them, so an empty
+// object disables the detection of stage kinds.
+func LoadLayout(name string) (*Layout, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := DefaultLayout()
+
+	// Decoding into a non-nil map would merge the stage kinds of the file
+	// into the default ones.
+	kinds := l.StageKinds
+	l.StageKinds = nil
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(l)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if l.StageKinds == nil {
+		l.StageKinds = kinds
+	}
+
+	_, err = l.compile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return l, nil
+}
+
+// compiledLayout contains the compiled selectors of a Layout.
+type compiledLayout struct {
+	day       selector
+	dayLabel  selector
+	stage     selector
+	stageName selector
+	event     selector
+	time      selector
+	title     selector
+
+	// link is nil if the element of the event itself carries the link.
+	link          selector
+	linkAttribute string
+
+	stageKinds map[string]string
+
+	// notice is nil if no announcements are extracted.
+	notice selector
+
+	// preliminaryPattern is nil if no day is marked as preliminary.
+	preliminaryPattern *regexp.Regexp
+
+	// id is nil if the ID is only extracted from the URL.
+	id          selector
+	idAttribute string
+
+	// idPattern is nil if no ID is extracted from the URL.
+	idPattern *regexp.Regexp
+
+	// copyrightPattern is nil if the copyright year is not used.
+	copyrightPattern *regexp.Regexp
+}
+
+// compile compiles the selectors of l.
+func (l *Layout) compile() (*compiledLayout, error) {
+	cl := &compiledLayout{
+		stageKinds:    l.StageKinds,
+		linkAttribute: l.LinkAttribute,
+		idAttribute:   l.IDAttribute,
+	}
+
+	ss := []struct {
+		s string
+		c *selector
+	}{
+		{l.Day, &cl.day},
+		{l.DayLabel, &cl.dayLabel},
+		{l.Stage, &cl.stage},
+		{l.StageName, &cl.stageName},
+		{l.Event, &cl.event},
+		{l.Time, &cl.time},
+		{l.Title, &cl.title},
+	}
+
+	for _, s := range ss {
+		var err error
+		*s.c, err = compileSelector(s.s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.Link != "" {
+		var err error
+		cl.link, err = compileSelector(l.Link)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.Notice != "" {
+		var err error
+		cl.notice, err = compileSelector(l.Notice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.PreliminaryPattern != "" {
+		var err error
+		cl.preliminaryPattern, err = regexp.Compile(l.PreliminaryPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preliminary pattern %q: %v", l.PreliminaryPattern, err)
+		}
+	}
+
+	if l.ID != "" {
+		var err error
+		cl.id, err = compileSelector(l.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.IDPattern != "" {
+		var err error
+		cl.idPattern, err = regexp.Compile(l.IDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID pattern %q: %v", l.IDPattern, err)
+		}
+		if cl.idPattern.NumSubexp() < 1 {
+			return nil, fmt.Errorf("invalid ID pattern %q: missing submatch", l.IDPattern)
+		}
+	}
+
+	if l.CopyrightPattern != "" {
+		var err error
+		cl.copyrightPattern, err = regexp.Compile(l.CopyrightPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid copyright pattern %q: %v", l.CopyrightPattern, err)
+		}
+		if cl.copyrightPattern.NumSubexp() < 1 {
+			return nil, fmt.Errorf("invalid copyright pattern %q: missing submatch", l.CopyrightPattern)
+		}
+	}
+
+	return cl, nil
+}
@@ -0,0 +1,166 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultLayoutCompiles(t *testing.T) {
+	_, err := DefaultLayout().compile()
+	if err != nil {
+		t.Errorf("default layout does not compile: %v", err)
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	l, err := LoadLayout("./testdata/alternative.layout.json")
+	if err != nil {
+		t.Fatalf("LoadLayout returned an unexpected error: %v", err)
+	}
+
+	if l.Day != "section.day" {
+		t.Errorf("unexpected day selector; is %q; expected %q", l.Day, "section.day")
+	}
+}
+
+func TestLoadLayoutStageKinds(t *testing.T) {
+	ts := []struct {
+		name     string
+		expected map[string]string
+	}{
+		{"./testdata/alternative.layout.json", DefaultLayout().StageKinds},
+		{"./testdata/stage_kinds.layout.json", map[string]string{"big": StageKindMain}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := LoadLayout(test.name)
+			if err != nil {
+				t.Fatalf("LoadLayout returned an unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(l.StageKinds, test.expected) {
+				t.Errorf("unexpected stage kinds; is %v; expected %v", l.StageKinds, test.expected)
+			}
+		})
+	}
+}
+
+func TestLoadLayoutInvalid(t *testing.T) {
+	l, err := LoadLayout("./testdata/invalid.layout.json")
+	if err == nil {
+		t.Fatalf("expected error did not occur: %v", l)
+	}
+}
+
+func TestLoadLayoutMissing(t *testing.T) {
+	_, err := LoadLayout("./testdata/missing.layout.json")
+	if err == nil {
+		t.Error("expected error did not occur")
+	}
+}
+
+func TestParseRunningOrderAlternativeLayout(t *testing.T) {
+	l, err := LoadLayout("./testdata/alternative.layout.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open("./testdata/alternative.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ro, err := ParseRunningOrderWithOptions(f, Year(2017), SiteLayout(l))
+	if err != nil {
+		t.Fatalf("ParseRunningOrderWithOptions returned an unexpected error: %v", err)
+	}
+
+	if len(ro.Days) != 1 || len(ro.Days[0].Stages) != 1 {
+		t.Fatalf("unexpected structure: %d days", len(ro.Days))
+	}
+
+	if is := ro.Days[0].Label; is != "Tuesday 25.07." {
+		t.Errorf("unexpected day label; is %q; expected %q", is, "Tuesday 25.07.")
+	}
+
+	s := ro.Days[0].Stages[0]
+	if is := s.Label; is != "Main Stage" {
+		t.Errorf("unexpected stage label; is %q; expected %q", is, "Main Stage")
+	}
+
+	expected := []testEvent{
+		{"22:30 - 00:00", &TimeStamps{Start: 1501014600, End: 1501020000}, "Amon Amarth", "AMON AMARTH", "http://example.com/bands/amon-amarth", ""},
+		{"00:10 - 01:20", &TimeStamps{Start: 1501020600, End: 1501024800}, "Kadavar", "KADAVAR", "http://example.com/bands/kadavar", ""},
+	}
+
+	if len(s.Events) != len(expected) {
+		t.Fatalf("unexpected number of events; is %d; expected %d", len(s.Events), len(expected))
+	}
+	for i, e := range s.Events {
+		if e.Time != expected[i].Time || e.Label != expected[i].Label || e.OriginalLabel != expected[i].OriginalLabel || e.URL != expected[i].URL {
+			t.Errorf("unexpected event %d; is %+v; expected %+v", i, *e, expected[i])
+		}
+
+		t.Run("check timestamps", compareTimeStampsPointers(e.TimeStamps, expected[i].TimeStamps))
+	}
+}
+
+func TestParseRunningOrderInvalidLayout(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = ParseRunningOrderWithOptions(f, SiteLayout(&Layout{Day: "["}))
+	if err == nil {
+		t.Error("expected error did not occur")
+	}
+}
+
+func TestLayoutCompileInvalidIDPattern(t *testing.T) {
+	for _, p := range []string{"(", "/b[0-9]+/"} {
+		l := DefaultLayout()
+		l.IDPattern = p
+
+		_, err := l.compile()
+		if err == nil {
+			t.Errorf("compile with ID pattern %q returns no error", p)
+		}
+	}
+}
+
+func TestLayoutCompileInvalidNotice(t *testing.T) {
+	l := DefaultLayout()
+	l.Notice = ".order_info >"
+	if _, err := l.compile(); err == nil {
+		t.Error("compile with invalid notice selector returns no error")
+	}
+
+	l = DefaultLayout()
+	l.PreliminaryPattern = "("
+	if _, err := l.compile(); err == nil {
+		t.Error("compile with invalid preliminary pattern returns no error")
+	}
+}
+
+func TestLayoutCompileInvalidCopyrightPattern(t *testing.T) {
+	for _, p := range []string{"(", "© [0-9]{4}"} {
+		l := DefaultLayout()
+		l.CopyrightPattern = p
+
+		_, err := l.compile()
+		if err == nil {
+			t.Errorf("compile with copyright pattern %q returns no error", p)
+		}
+	}
+}
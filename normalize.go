@@ -0,0 +1,153 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// A Normalizer turns the label of an event, as found in the running order,
+// into the spelling exposed in Event.Label.
+type Normalizer interface {
+	Normalize(label string) string
+}
+
+// The NormalizerFunc type is an adapter to allow the use of ordinary functions
+// as Normalizers.
+type NormalizerFunc func(label string) string
+
+// Normalize returns f(label).
+func (f NormalizerFunc) Normalize(label string) string {
+	return f(label)
+}
+
+// TitleCase is the default Normalizer. It lowercases the label and converts
+// the first letter of every word to title case. Words are separated by any
+// character that is neither a letter, a digit, a mark nor an apostrophe, so
+// "GUNS N' ROSES" becomes "Guns N' Roses", but "AC/DC" becomes "Ac/Dc". Use a
+// Dictionary to fix the spelling of such names.
+var TitleCase Normalizer = NormalizerFunc(func(label string) string {
+	return titleCase(strings.ToLower(label))
+})
+
+// titleCase converts the first letter of every word in s to title case. All
+// other letters are left untouched.
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	start := true
+	for _, r := range s {
+		if start {
+			b.WriteRune(unicode.ToTitle(r))
+		} else {
+			b.WriteRune(r)
+		}
+
+		start = isWordSeparator(r)
+	}
+
+	return b.String()
+}
+
+// isWordSeparator returns true if r separates two words.
+func isWordSeparator(r rune) bool {
+	switch r {
+	case '\'', '’', '_':
+		return false
+	}
+
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+}
+
+// A Dictionary maps labels to their canonical spellings. Labels are looked up
+// case insensitively and regardless of whitespace.
+type Dictionary map[string]string
+
+// NewDictionary returns a Dictionary containing the canonical spellings
+// spellings.
+func NewDictionary(spellings ...string) Dictionary {
+	d := Dictionary{}
+	for _, s := range spellings {
+		d.Add(s)
+	}
+
+	return d
+}
+
+// LoadDictionary reads a Dictionary from the file name. The file contains one
+// canonical spelling per line, e.g. "AC/DC". Empty lines and lines starting
+// with "#" are ignored.
+func LoadDictionary(name string) (Dictionary, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := Dictionary{}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		d.Add(l)
+	}
+
+	err = s.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return d, nil
+}
+
+// Add adds the canonical spelling s to d.
+func (d Dictionary) Add(s string) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return
+	}
+
+	d[dictionaryKey(s)] = s
+}
+
+// Lookup returns the canonical spelling of label. If d does not contain label,
+// ok is false.
+func (d Dictionary) Lookup(label string) (s string, ok bool) {
+	s, ok = d[dictionaryKey(label)]
+	return s, ok
+}
+
+// Normalizer returns a Normalizer that returns the canonical spelling of
+// labels contained in d and the result of fallback for all other labels. If
+// fallback is nil, TitleCase is used.
+func (d Dictionary) Normalizer(fallback Normalizer) Normalizer {
+	if fallback == nil {
+		fallback = TitleCase
+	}
+
+	return NormalizerFunc(func(label string) string {
+		if s, ok := d.Lookup(label); ok {
+			return s
+		}
+
+		return fallback.Normalize(label)
+	})
+}
+
+// dictionaryKey returns the key of label in a Dictionary.
+func dictionaryKey(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
@@ -0,0 +1,120 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTitleCase(t *testing.T) {
+	ts := []struct {
+		label    string
+		expected string
+	}{
+		{"Turbowarrior of steel", "Turbowarrior Of Steel"},
+		{"AMON AMARTH", "Amon Amarth"},
+		{"GUNS N' ROSES", "Guns N' Roses"},
+		{"o’brien", "O’brien"},
+		{"ac/dc", "Ac/Dc"},
+		{"boško bursać", "Boško Bursać"},
+		{"ÆTHER REALM", "Æther Realm"},
+		{"“lemmy” kilmister", "“Lemmy” Kilmister"},
+		{"ǆemo", "ǅemo"},
+		{"1349", "1349"},
+		{"", ""},
+	}
+
+	for _, test := range ts {
+		t.Run(test.label, func(t *testing.T) {
+			if is := TitleCase.Normalize(test.label); is != test.expected {
+				t.Errorf("unexpected normalized label; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestDictionaryNormalizer(t *testing.T) {
+	n := NewDictionary("AC/DC", " Turbowarrior   of Steel ", "").Normalizer(nil)
+
+	ts := []struct {
+		label    string
+		expected string
+	}{
+		{"Ac/Dc", "AC/DC"},
+		{"TURBOWARRIOR OF  STEEL", "Turbowarrior of Steel"},
+		{"KATATONIA", "Katatonia"},
+	}
+
+	for _, test := range ts {
+		t.Run(test.label, func(t *testing.T) {
+			if is := n.Normalize(test.label); is != test.expected {
+				t.Errorf("unexpected normalized label; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestDictionaryNormalizerFallback(t *testing.T) {
+	n := NewDictionary("AC/DC").Normalizer(NormalizerFunc(func(label string) string {
+		return "fallback"
+	}))
+
+	if is := n.Normalize("ac/dc"); is != "AC/DC" {
+		t.Errorf("unexpected normalized label; is %q; expected %q", is, "AC/DC")
+	}
+	if is := n.Normalize("Doro"); is != "fallback" {
+		t.Errorf("unexpected normalized label; is %q; expected %q", is, "fallback")
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	d, err := LoadDictionary("./testdata/names.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d) != 1 {
+		t.Errorf("unexpected number of spellings; is %d; expected 1", len(d))
+	}
+
+	s, ok := d.Lookup("turbowarrior of steel")
+	if !ok || s != "Turbowarrior of Steel" {
+		t.Errorf("unexpected spelling; is %q (%t); expected %q", s, ok, "Turbowarrior of Steel")
+	}
+
+	_, err = LoadDictionary("./testdata/missing.txt")
+	if err == nil {
+		t.Error("expected error did not occur")
+	}
+}
+
+func TestParseRunningOrderWithLabelNormalizer(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := LoadDictionary("./testdata/names.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := ParseRunningOrderWithOptions(f, Year(2017), LabelNormalizer(d.Normalizer(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := ro.Days[0].Stages[0].Events[1]
+	if e.Label != "Turbowarrior of Steel" {
+		t.Errorf("unexpected label; is %q; expected %q", e.Label, "Turbowarrior of Steel")
+	}
+	if e.OriginalLabel != "Turbowarrior of steel" {
+		t.Errorf("unexpected original label; is %q; expected %q", e.OriginalLabel, "Turbowarrior of steel")
+	}
+}
@@ -0,0 +1,107 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import "time"
+
+// A StageStatus describes what is happening on a stage at a given instant.
+type StageStatus struct {
+	// Stage is the label of the stage.
+	Stage string `json:"stage"`
+
+	// Kind is the kind of the stage (see Stage.Kind).
+	Kind string `json:"kind,omitempty"`
+
+	// Playing is the event taking place on the stage. It is nil if nothing
+	// is taking place.
+	Playing *Event `json:"playing"`
+
+	// Next is the next event starting on the stage. It is nil if there is
+	// no further event.
+	Next *Event `json:"next"`
+
+	// MinutesUntilNext is the number of minutes until Next starts, rounded
+	// up. It is 0 if Next is nil.
+	MinutesUntilNext int `json:"minutes_until_next,omitempty"`
+}
+
+// NowPlaying returns the status of every stage of ro at the instant at. Stages
+// are identified by their label across all days and returned in the order of
+// their first appearance.
+//
+// An event is playing from its start until its end. An event without an end
+// is playing until the next event on its stage starts or, if there is none,
+// until the end of the span of its day. Events without timestamps are
+// ignored.
+func NowPlaying(ro *RunningOrder, at time.Time) []*StageStatus {
+	now := at.Unix()
+
+	var stages []*StageStatus
+	byLabel := map[string]*StageStatus{}
+
+	for _, d := range ro.Days {
+		for _, s := range d.Stages {
+			st, ok := byLabel[s.Label]
+			if !ok {
+				st = &StageStatus{Stage: s.Label, Kind: s.Kind}
+				byLabel[s.Label] = st
+				stages = append(stages, st)
+			}
+
+			for _, e := range s.Events {
+				if e.TimeStamps == nil {
+					continue
+				}
+
+				start := e.TimeStamps.Start
+				if start > now {
+					if st.Next == nil || start < st.Next.TimeStamps.Start {
+						st.Next = e
+					}
+					continue
+				}
+
+				if now < eventEnd(d, s, e) {
+					st.Playing = e
+				}
+			}
+		}
+	}
+
+	for _, st := range stages {
+		if st.Next != nil {
+			st.MinutesUntilNext = int((st.Next.TimeStamps.Start - now + 59) / 60)
+		}
+	}
+
+	return stages
+}
+
+// eventEnd returns the end of the event e on stage s on day d. If e has no
+// end, the start of the next event on s or, if there is none, the end of the
+// span of d is returned.
+func eventEnd(d *Day, s *Stage, e *Event) int64 {
+	if e.TimeStamps.End != 0 {
+		return e.TimeStamps.End
+	}
+
+	var end int64
+	if d.Span != nil {
+		end = d.Span.End
+	}
+
+	for _, f := range s.Events {
+		if f.TimeStamps == nil || f.TimeStamps.Start <= e.TimeStamps.Start {
+			continue
+		}
+		if end == 0 || f.TimeStamps.Start < end {
+			end = f.TimeStamps.Start
+		}
+	}
+
+	return end
+}
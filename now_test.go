@@ -0,0 +1,145 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNowPlaying(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ro, err := ParseRunningOrder(2017, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := time.LoadLocation("Europe/Ljubljana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type status struct {
+		playing, next string
+		minutes       int
+	}
+
+	ts := []struct {
+		name     string
+		at       time.Time
+		expected []status
+	}{
+		{
+			"before",
+			time.Date(2017, 7, 20, 12, 0, 0, 0, loc),
+			[]status{{"", "", 0}, {"", "Katatonia", 7725}, {"", "Kadavar", 7930}},
+		},
+		{
+			"playing",
+			time.Date(2017, 7, 25, 21, 0, 0, 0, loc),
+			[]status{{"", "", 0}, {"Katatonia", "Amon Amarth", 90}, {"", "Kadavar", 190}},
+		},
+		{
+			"break",
+			time.Date(2017, 7, 25, 22, 15, 30, 0, loc),
+			[]status{{"", "", 0}, {"", "Amon Amarth", 15}, {"", "Kadavar", 115}},
+		},
+		{
+			"after_midnight",
+			time.Date(2017, 7, 26, 0, 30, 0, 0, loc),
+			[]status{{"", "", 0}, {"", "Doro", 1320}, {"Kadavar", "", 0}},
+		},
+		{
+			"end_is_exclusive",
+			time.Date(2017, 7, 26, 1, 20, 0, 0, loc),
+			[]status{{"", "", 0}, {"", "Doro", 1270}, {"", "", 0}},
+		},
+		{
+			"after",
+			time.Date(2017, 7, 28, 12, 0, 0, 0, loc),
+			[]status{{"", "", 0}, {"", "", 0}, {"", "", 0}},
+		},
+	}
+
+	label := func(e *Event) string {
+		if e == nil {
+			return ""
+		}
+		return e.Label
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			ss := NowPlaying(ro, test.at)
+
+			if len(ss) != 3 {
+				t.Fatalf("unexpected number of stages; is %d; expected %d", len(ss), 3)
+			}
+			if ss[0].Stage != "Newforces Stage" || ss[1].Stage != "Ian Fraser “Lemmy” Kilmister Stage" || ss[2].Stage != "Boško Bursać Stage" {
+				t.Errorf("unexpected stages: %q, %q, %q", ss[0].Stage, ss[1].Stage, ss[2].Stage)
+			}
+
+			for i, s := range ss {
+				is := status{label(s.Playing), label(s.Next), s.MinutesUntilNext}
+				if is != test.expected[i] {
+					t.Errorf("unexpected status of stage %q; is %v; expected %v", s.Stage, is, test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNowPlayingOpenEnd(t *testing.T) {
+	event := func(label string, start, end int64) *Event {
+		return &Event{"", &TimeStamps{Start: start, End: end}, label, label, "", ""}
+	}
+
+	ro := &RunningOrder{Days: []*Day{{
+		"Saturday 22.07.",
+		[]*Stage{
+			{"Main Stage", []*Event{
+				event("Late", 1500757200, 0),
+				event("Early", 1500748200, 0),
+			}, StageKindMain, 1},
+		},
+		nil,
+		&TimeStamps{Start: 1500710400, End: 1500796800},
+		false,
+		nil,
+	}}}
+
+	ts := []struct {
+		name     string
+		at       int64
+		expected string
+	}{
+		{"until_next", 1500757199, "Early"},
+		{"next", 1500757200, "Late"},
+		{"until_end_of_day", 1500796799, "Late"},
+		{"end_of_day", 1500796800, ""},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			s := NowPlaying(ro, time.Unix(test.at, 0))[0]
+
+			is := ""
+			if s.Playing != nil {
+				is = s.Playing.Label
+			}
+			if is != test.expected {
+				t.Errorf("unexpected event playing; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// defaultLocation is the time.Location where the MetalDays festival happens.
+var defaultLocation *time.Location
+
+// init initializes the defaults of the parser options.
+func init() {
+	var err error
+	defaultLocation, err = time.LoadLocation("Europe/Ljubljana")
+	if err != nil {
+		panic(err)
+	}
+}
+
+const (
+	// DefaultDateFormat is the time package layout of the day labels on
+	// the MetalDays website.
+	DefaultDateFormat = "Monday 02.01."
+
+	// DefaultRolloverCutoff is the time of day before which events are
+	// assumed to belong to the night after their day.
+	DefaultRolloverCutoff = 10 * time.Hour
+)
+
+// options contains the settings used while parsing a running order.
+type options struct {
+	// year is the year the festival takes place in. If it is 0, the year
+	// is inferred from the running order.
+	year int
+
+	// referenceYear is the year the inferred year is searched around if
+	// the running order contains no copyright year.
+	referenceYear int
+
+	// location is the time.Location where the festival takes place.
+	location *time.Location
+
+	// rolloverCutoff is the time of day before which events belong to
+	// the next calendar day.
+	rolloverCutoff time.Duration
+
+	// dateFormat is the time package layout of the day labels.
+	dateFormat string
+
+	// lenient tells the parser to skip unparsable parts of the running
+	// order instead of failing.
+	lenient bool
+
+	// layout describes where the parts of the running order can be found.
+	layout *Layout
+
+	// normalizer normalizes the labels of the events.
+	normalizer Normalizer
+
+	// schema is the version of the output schema.
+	schema int
+
+	// selectors contains the compiled selectors of layout.
+	selectors *compiledLayout
+
+	// err contains the reason why the options are invalid, e.g. if the
+	// selectors of layout can not be compiled.
+	err error
+}
+
+// An Option configures how a running order is parsed.
+type Option func(*options)
+
+// newOptions returns options initialized with the defaults for the current
+// MetalDays festival and modified by opts.
+func newOptions(opts ...Option) *options {
+	o := &options{
+		referenceYear:  time.Now().Year(),
+		location:       defaultLocation,
+		rolloverCutoff: DefaultRolloverCutoff,
+		dateFormat:     DefaultDateFormat,
+		layout:         DefaultLayout(),
+		normalizer:     TitleCase,
+		schema:         SchemaV1,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.location == nil {
+		o.location = time.UTC
+	}
+
+	if o.normalizer == nil {
+		o.normalizer = TitleCase
+	}
+
+	if o.schema < SchemaV1 || o.schema > LatestSchema {
+		o.err = fmt.Errorf("mdjson: unknown schema version %d", o.schema)
+		return o
+	}
+
+	if o.layout == nil {
+		o.err = errors.New("mdjson: no layout given")
+		return o
+	}
+	o.selectors, o.err = o.layout.compile()
+
+	return o
+}
+
+// Year sets the year the festival takes place in. The default, and a year of
+// 0, is to infer the year from the running order: the year is chosen so that
+// the weekdays in the day labels match their dates, preferring the copyright
+// year found in the page or, if there is none, the current year.
+//
+// If the weekday of a day label does not match its date in the year, this is
+// reported as a *ParseError of KindYear in the Warnings of the running order.
+// The running order is parsed anyway, even in strict mode.
+func Year(year int) Option {
+	return func(o *options) {
+		o.year = year
+	}
+}
+
+// Location sets the time.Location where the festival takes place. The default
+// is Europe/Ljubljana. A nil loc is treated as time.UTC.
+func Location(loc *time.Location) Option {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// RolloverCutoff sets the time of day before which events are assumed to
+// belong to the night after their day, e.g. an event at 00:30 with a cutoff
+// of 10 hours is placed on the calendar day following its day. The default is
+// DefaultRolloverCutoff. A cutoff of 0 disables the rollover.
+func RolloverCutoff(d time.Duration) Option {
+	return func(o *options) {
+		o.rolloverCutoff = d
+	}
+}
+
+// DateFormat sets the layout, as understood by the time package, of the day
+// labels. The year component is ignored. The default is DefaultDateFormat.
+func DateFormat(layout string) Option {
+	return func(o *options) {
+		o.dateFormat = layout
+	}
+}
+
+// Lenient enables or disables the lenient mode. In lenient mode unparsable
+// days, stages and events are skipped or only partially filled and the
+// problems are collected in RunningOrder.Warnings instead of failing the
+// whole parse.
+func Lenient(lenient bool) Option {
+	return func(o *options) {
+		o.lenient = lenient
+	}
+}
+
+// SiteLayout sets the Layout describing where the parts of the running order
+// can be found in the HTML document. The default is DefaultLayout.
+func SiteLayout(l *Layout) Option {
+	return func(o *options) {
+		o.layout = l
+	}
+}
+
+// LabelNormalizer sets the Normalizer used for the labels of the events. The
+// default is TitleCase. A nil n is treated as TitleCase.
+func LabelNormalizer(n Normalizer) Option {
+	return func(o *options) {
+		o.normalizer = n
+	}
+}
+
+// ParseRunningOrderWithOptions parses the HTML running order in r and returns a
+// fully populated RunningOrder. The parser is configured by opts; without any
+// options the running order of the current MetalDays festival is expected.
+//
+// If the running order can not be parsed, the returned error is a *ParseError.
+// If the options are invalid, e.g. if the selectors of the Layout can not be
+// compiled, a plain error is returned.
+func ParseRunningOrderWithOptions(r io.Reader, opts ...Option) (*RunningOrder, error) {
+	return parseRunningOrder(r, newOptions(opts...))
+}
@@ -0,0 +1,143 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewOptionsDefaults(t *testing.T) {
+	o := newOptions()
+
+	if o.year != 0 {
+		t.Errorf("unexpected default year; is %d; expected 0", o.year)
+	}
+	if o.referenceYear != time.Now().Year() {
+		t.Errorf("unexpected default reference year; is %d; expected %d", o.referenceYear, time.Now().Year())
+	}
+	if o.location != defaultLocation {
+		t.Errorf("unexpected default location; is %v; expected %v", o.location, defaultLocation)
+	}
+	if o.rolloverCutoff != DefaultRolloverCutoff {
+		t.Errorf("unexpected default rollover cutoff; is %v; expected %v", o.rolloverCutoff, DefaultRolloverCutoff)
+	}
+	if o.dateFormat != DefaultDateFormat {
+		t.Errorf("unexpected default date format; is %q; expected %q", o.dateFormat, DefaultDateFormat)
+	}
+	if o.lenient {
+		t.Error("lenient mode enabled by default")
+	}
+	if o.schema != SchemaV1 {
+		t.Errorf("unexpected default schema; is %d; expected %d", o.schema, SchemaV1)
+	}
+	if is := o.normalizer.Normalize("AMON AMARTH"); is != "Amon Amarth" {
+		t.Errorf("unexpected label from default normalizer; is %q; expected %q", is, "Amon Amarth")
+	}
+}
+
+func TestParseRunningOrderWithOptions(t *testing.T) {
+	ts := []struct {
+		opts     []Option
+		day      *TimeStamps
+		kadavar  *TimeStamps
+		expected string
+	}{
+		{
+			[]Option{Year(2017)},
+			&TimeStamps{Start: 1500933600, End: 1501020000},
+			&TimeStamps{Start: 1501020600, End: 1501024800},
+			"default",
+		},
+		{
+			[]Option{Year(2017), Location(time.UTC)},
+			&TimeStamps{Start: 1500940800, End: 1501027200},
+			&TimeStamps{Start: 1501027800, End: 1501032000},
+			"utc",
+		},
+		{
+			[]Option{Year(2017), Location(time.UTC), RolloverCutoff(0)},
+			&TimeStamps{Start: 1500940800, End: 1501027200},
+			&TimeStamps{Start: 1500941400, End: 1500945600},
+			"utc_without_rollover",
+		},
+		{
+			[]Option{Year(2016), RolloverCutoff(0)},
+			&TimeStamps{Start: 1469397600, End: 1469484000},
+			&TimeStamps{Start: 1469398200, End: 1469402400},
+			"2016_without_rollover",
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.expected, func(t *testing.T) {
+			f, err := os.Open("./testdata/sample.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ro, err := ParseRunningOrderWithOptions(f, test.opts...)
+			if err != nil {
+				t.Fatalf("ParseRunningOrderWithOptions returns unexpected error: %v", err)
+			}
+
+			day := ro.Days[1]
+			t.Run(fmt.Sprintf("check timestamps for day %q", day.Label),
+				compareTimeStampsPointers(day.TimeStamps, test.day))
+
+			event := day.Stages[1].Events[0]
+			t.Run(fmt.Sprintf("check timestamps for event %q", event.Label),
+				compareTimeStampsPointers(event.TimeStamps, test.kadavar))
+		})
+	}
+}
+
+func TestParseRunningOrderWithOptionsDateFormat(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = ParseRunningOrderWithOptions(f, DateFormat("02.01.2006"))
+	if err == nil {
+		t.Error("expected error did not occur")
+	}
+}
+
+func TestParseRunningOrderWithNilOptions(t *testing.T) {
+	ts := []struct {
+		opt   Option
+		valid bool
+		name  string
+	}{
+		{Location(nil), true, "nil_location"},
+		{SiteLayout(nil), false, "nil_layout"},
+		{LabelNormalizer(nil), true, "nil_normalizer"},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.Open("./testdata/sample.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ro, err := ParseRunningOrderWithOptions(f, Year(2017), test.opt)
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if (ro != nil) != test.valid {
+				t.Errorf("unexpected running order: %v", ro)
+			}
+		})
+	}
+}
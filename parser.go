@@ -7,6 +7,7 @@
 package mdjson
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"time"
@@ -25,67 +26,27 @@ type Day struct {
 	// TimeStamps contains the timestamps for the start and the end of the day.
 	TimeStamps *TimeStamps `json:"timestamps"`
 
-	// node is the *html.Node associated with the day. It is intended to be
-	// used as input to getStages.
-	node *html.Node
-}
+	// Span contains the timestamps for the start and the end of the
+	// festival day. Unlike TimeStamps, which span the calendar day, Span is
+	// shifted by the rollover cutoff, so it contains all events of the day,
+	// including those after midnight.
+	Span *TimeStamps `json:"span"`
 
-// getDays walks the running order starting at n and returns a slice of found
-// Days. year should be the year the days belong to.
-func getDays(year int, n *html.Node) ([]*Day, error) {
-	d := make(chan *Day)
-	e := make(chan error)
-	done := make(chan bool)
-
-	go protect(e, done, func() {
-		getDaysRecursive(year, n, d)
-	})
-
-	days := []*Day{}
-	for {
-		select {
-		case dd := <-d:
-			days = append(days, dd)
-		case err := <-e:
-			return nil, err
-		case <-done:
-			return days, nil
-		}
-	}
-}
+	// Preliminary is true if the announcements state that the running
+	// order of the day is preliminary and may still change.
+	Preliminary bool `json:"preliminary"`
 
-// getDaysRecursive is used by GetDays (and by itself) to walk the running
-// order recursively starting at n. Any Day found is published via d. year
-// should be the year the days belong to.
-func getDaysRecursive(year int, n *html.Node, d chan<- *Day) {
-	if n.Type == html.ElementNode && hasAttributeValue(n.Attr, "class", "lineup_day") {
-		nn := newNode(n)
-		datenode := nn.firstNonEmptyChild().nextNonEmptySibling().firstNonEmptyChild().firstNonEmptyChild()
-		if datenode == nil {
-			panic("Unable to parse running order structure (day).")
-		}
-
-		// For some reason there is an additional space behind each date
-		// separator
-		date := strings.Replace(datenode.Data, ". ", ".", -1)
-		date = strings.TrimSpace(date)
-
-		day := &Day{date, []*Stage{}, nil, n}
-
-		e := addTimeStampsToDay(year, day)
-		if e != nil {
-			panic(e)
-		}
-
-		d <- day
-		return
-	}
-
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		getDaysRecursive(year, c, d)
-	}
+	// Announcements contains the notices published for the day, e.g.
+	// "Running order is preliminary and changes are still possible!".
+	Announcements []string `json:"announcements,omitempty"`
 }
 
+// The kinds of stages known by the DefaultLayout.
+const (
+	StageKindMain   = "main"
+	StageKindSecond = "second"
+)
+
 // A Stage represents a stage.
 type Stage struct {
 	// Label contains the name of the stage.
@@ -94,56 +55,14 @@ type Stage struct {
 	// Events contains the events that will take on the stage.
 	Events []*Event `json:"events"`
 
-	// node is the *html.Node associated with the stage. It is intended to
-	// be used as input for getEvents.
-	node *html.Node
-}
+	// Kind contains the normalized kind of the stage, e.g. StageKindMain.
+	// Unlike the Label, which may change from year to year, the Kind is
+	// stable. Kind is empty if it can not be determined.
+	Kind string `json:"kind,omitempty"`
 
-// getStages walks the running order starting at n and returns a slice of found
-// Stages. In order to get the stages for one day, n should be the node
-// associated with a Day.
-func getStages(n *html.Node) ([]*Stage, error) {
-	s := make(chan *Stage)
-	e := make(chan error)
-	done := make(chan bool)
-
-	go protect(e, done, func() {
-		getStagesRecursive(n, s)
-	})
-
-	stages := []*Stage{}
-	for {
-		select {
-		case ss := <-s:
-			stages = append(stages, ss)
-		case err := <-e:
-			return nil, err
-		case <-done:
-			return stages, nil
-		}
-	}
-}
-
-// getStagesRecursive is used by GetStages (and by itself) to walk the running
-// order recursively starting at n. Any Stage found is published via s.
-func getStagesRecursive(n *html.Node, s chan<- *Stage) {
-	if n.Type == html.ElementNode && hasAttributeValue(n.Attr, "class", "lineup_stage") {
-		nn := newNode(n)
-		namenode := nn.firstNonEmptyChild().firstNonEmptyChild().nextNonEmptySibling().firstNonEmptyChild()
-		if namenode == nil {
-			panic("Unable to parse running order structure (stage).")
-		}
-
-		name := strings.TrimSpace(namenode.Data)
-		name = strings.Title(name)
-
-		s <- &Stage{name, []*Event{}, n}
-		return
-	}
-
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		getStagesRecursive(c, s)
-	}
+	// Position contains the position of the stage on its day in the order
+	// of the running order, starting at 1.
+	Position int `json:"position"`
 }
 
 // A Event represents an event.
@@ -154,124 +73,348 @@ type Event struct {
 	// TimeStamps contains the timestamps for the start and the end of the event.
 	TimeStamps *TimeStamps `json:"timestamps"`
 
-	// Label contains the name of the event, normally the name of a band.
+	// Label contains the normalized name of the event, normally the name of
+	// a band (see LabelNormalizer).
 	Label string `json:"label"`
 
+	// OriginalLabel contains the name of the event as found in the running
+	// order.
+	OriginalLabel string `json:"original_label"`
+
 	// URL contains a string representation of an URL that points to
 	// additional information about the event.
 	URL string `json:"url"`
+
+	// ID contains the ID the festival uses for the band. It is more stable
+	// than the Label and can be used to identify the event across running
+	// orders. ID is empty if it can not be determined.
+	ID string `json:"id,omitempty"`
 }
 
-// getEvents walks the running order starting at n and returns a slice of found
-// Events.
-// The time.Time d that denotes the start for the day of the event will be used
-// to generate the timestamps for the event.
-// In order to get the events for one stage, n should be the node associated
-// with a Stage.
-func getEvents(n *html.Node, d time.Time) ([]*Event, error) {
-	ev := make(chan *Event)
-	e := make(chan error)
-	done := make(chan bool)
-
-	go protect(e, done, func() {
-		getEventsRecursive(n, d, ev)
-	})
-
-	events := []*Event{}
-	for {
-		select {
-		case ee := <-ev:
-			events = append(events, ee)
-		case err := <-e:
-			return nil, err
-		case <-done:
-			return events, nil
-		}
+// A RunningOrder contains the Days of the event.
+type RunningOrder struct {
+	Days []*Day `json:"days"`
+
+	// Year contains the year the festival takes place in, either as given
+	// by the Year option or as inferred from the running order. If the
+	// festival lasts over New Year, Year is the year of the first day.
+	Year int `json:"year"`
+
+	// Schema contains the version of the output schema (see Schema).
+	Schema int `json:"schema"`
+
+	// Preliminary is true if the running order of any day is preliminary.
+	Preliminary bool `json:"preliminary"`
+
+	// Warnings contains the problems that were encountered while parsing
+	// the running order in lenient mode. Day labels whose weekday does not
+	// match their date in Year and events that are not inside the span of
+	// their day are reported here in strict mode as well.
+	Warnings []*ParseError `json:"-"`
+}
+
+// A walker collects the days, stages and events of a running order in a single
+// traversal of the HTML document.
+type walker struct {
+	// o configures the walker.
+	o *options
+
+	// ro receives the days found and, in lenient mode, the warnings.
+	ro *RunningOrder
+
+	// day and stage are the day and the stage the walker is currently in.
+	// They are nil outside of any day or stage.
+	day   *Day
+	stage *Stage
+
+	// years assigns the years to the days.
+	years *yearTracker
+
+	// dayStart denotes the start of day. It is the zero time.Time if the
+	// timestamps of day could not be generated.
+	dayStart time.Time
+}
+
+// walk walks the running order recursively starting at n. Days are only
+// searched outside of any day, stages only inside of a day and events only
+// inside of a stage.
+//
+// Problems are handled by report: in strict mode the first problem aborts the
+// walk and is returned, in lenient mode the unparsable part is skipped or only
+// partially filled.
+func (w *walker) walk(n *html.Node) error {
+	switch {
+	case w.day == nil && w.o.selectors.day.match(n):
+		return w.walkDay(n)
+	case w.day != nil && w.stage == nil && w.o.selectors.stage.match(n):
+		return w.walkStage(n)
+	case w.day != nil && w.stage == nil && w.o.selectors.notice != nil && w.o.selectors.notice.match(n):
+		w.addNotice(n)
+		return nil
+	case w.stage != nil && w.o.selectors.event.match(n):
+		return w.addEvent(n)
 	}
+
+	return w.walkChildren(n)
 }
 
-// getEventsRecursive is used by GetEvents (and by itself) to walk the running
-// order recursively starting at n.
-// The time.Time d that denotes the start for the day of the event will be used
-// to generate the timestamps for the even.
-// Any Event found is published via e.
-func getEventsRecursive(n *html.Node, d time.Time, e chan<- *Event) {
-	if n.Type == html.ElementNode && hasAttributeValue(n.Attr, "class", "band_lineup") {
-		nn := newNode(n)
-
-		timenode := nn.firstNonEmptyChild().nextNonEmptySibling()
-		namenode := nn.firstNonEmptyChild().nextNonEmptySibling().nextNonEmptySibling()
-
-		// At the moment the DOM of the Newforces stage is different.
-		if timenode == nil || !hasAttributeValue(timenode.Attr, "class", "time") {
-			timenode = nn.firstNonEmptyChild()
-			namenode = nn.firstNonEmptyChild().nextNonEmptySibling()
+// walkChildren walks all children of n.
+func (w *walker) walkChildren(n *html.Node) error {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		err := w.walk(c)
+		if err != nil {
+			return err
 		}
+	}
 
-		timenode = timenode.firstNonEmptyChild()
-		namenode = namenode.firstNonEmptyChild()
+	return nil
+}
 
-		if namenode == nil || timenode == nil {
-			panic("Unable to parse running order structure (event).")
-		}
+// walkDay adds the day found at n to the running order and walks its stages.
+func (w *walker) walkDay(n *html.Node) error {
+	date := dayLabel(n, w.o)
+	if date == "" {
+		return w.report(newParseError(KindDay, n, ErrUnexpectedStructure))
+	}
 
-		time := strings.TrimSpace(timenode.Data)
-		name := strings.TrimSpace(namenode.Data)
-		name = strings.ToLower(name)
-		name = strings.Title(name)
+	day := &Day{date, []*Stage{}, nil, nil, false, nil}
+	w.dayStart = time.Time{}
 
-		url := getAttributeValue(n.Attr, "href")
+	year := w.years.yearOf(date, w.o)
 
-		event := &Event{time, nil, name, url}
-		err := addTimeStampsToEvent(event, d)
+	err := addTimeStampsToDay(day, year, w.o)
+	if err != nil {
+		err = w.report(newParseError(KindTimeStamp, n, err))
 		if err != nil {
-			panic(err)
+			return err
 		}
+	} else {
+		w.dayStart = time.Unix(day.TimeStamps.Start, 0).In(w.o.location)
+
+		// A day label conflicting with the year is no reason to give
+		// up, even in strict mode: the year may have been given
+		// explicitly and the timestamps are still generated for it.
+		if !labelMatchesYear(date, year, w.o) {
+			w.ro.Warnings = append(w.ro.Warnings, newParseError(KindYear, n, fmt.Errorf("%q is no valid date in %d", date, year)))
+		}
+	}
+
+	w.ro.Days = append(w.ro.Days, day)
+
+	w.day = day
+	defer func() { w.day = nil }()
 
-		e <- event
-		return
+	return w.walkChildren(n)
+}
+
+// addNotice adds the announcements of the notice found at n to the current day.
+func (w *walker) addNotice(n *html.Node) {
+	for _, a := range noticeTexts(n) {
+		w.day.Announcements = append(w.day.Announcements, a)
+
+		if w.o.selectors.preliminaryPattern != nil && w.o.selectors.preliminaryPattern.MatchString(a) {
+			w.day.Preliminary = true
+			w.ro.Preliminary = true
+		}
 	}
+}
 
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		getEventsRecursive(c, d, e)
+// walkStage adds the stage found at n to the current day and walks its events.
+func (w *walker) walkStage(n *html.Node) error {
+	name := elementText(w.o.selectors.stageName.first(n))
+	if name == "" {
+		return w.report(newParseError(KindStage, n, ErrUnexpectedStructure))
 	}
+
+	name = titleCase(name)
+
+	stage := &Stage{name, []*Event{}, w.stageKind(n), len(w.day.Stages) + 1}
+	w.day.Stages = append(w.day.Stages, stage)
+
+	w.stage = stage
+	defer func() { w.stage = nil }()
+
+	return w.walkChildren(n)
 }
 
-// A RunningOrder contains the Days of the event.
-type RunningOrder struct {
-	Days []*Day `json:"days"`
+// stageKind returns the kind of the stage found at n. The classes of n and its
+// descendants are looked up in the stage kinds of the layout in document
+// order. An empty string is returned if no class is found.
+func (w *walker) stageKind(n *html.Node) string {
+	if n.Type == html.ElementNode {
+		for _, c := range strings.Fields(getAttributeValue(n.Attr, "class")) {
+			if k, ok := w.o.selectors.stageKinds[c]; ok {
+				return k
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if k := w.stageKind(c); k != "" {
+			return k
+		}
+	}
+
+	return ""
 }
 
-// ParseRunningOrder parses the HTML running order in r and returns a fully
-// populated RunningOrder. year is the year in which the festival takes place;
-// generally this should be time.Now().Year().
-func ParseRunningOrder(year int, r io.Reader) (*RunningOrder, error) {
-	n, err := html.Parse(r)
+// addEvent adds the event found at n to the current stage.
+func (w *walker) addEvent(n *html.Node) error {
+	time := elementText(w.o.selectors.time.first(n))
+	name := elementText(w.o.selectors.title.first(n))
+	if name == "" || time == "" {
+		return w.report(newParseError(KindEvent, n, ErrUnexpectedStructure))
+	}
+
+	linknode := n
+	if w.o.selectors.link != nil {
+		linknode = w.o.selectors.link.first(n)
+	}
+
+	var url string
+	if linknode != nil {
+		url = getAttributeValue(linknode.Attr, w.o.selectors.linkAttribute)
+	}
+
+	event := &Event{time, nil, w.o.normalizer.Normalize(name), name, url, w.eventID(n, url)}
+	w.stage.Events = append(w.stage.Events, event)
+
+	// Without the date of the day (which has already been reported) no
+	// timestamps can be generated for the event.
+	if w.dayStart.IsZero() {
+		return nil
+	}
+
+	err := addTimeStampsToEvent(event, w.dayStart, w.o.rolloverCutoff)
 	if err != nil {
-		return nil, err
+		return w.report(newParseError(KindTimeStamp, n, err))
+	}
+
+	// A set reaching past its day is no reason to give up, even in strict
+	// mode: its timestamps are still sensible and Validate reports it as
+	// CheckOutsideDay.
+	err = checkEventInDay(event, w.day)
+	if err != nil {
+		w.ro.Warnings = append(w.ro.Warnings, newParseError(KindTimeStamp, n, err))
 	}
 
-	ro := &RunningOrder{}
+	return nil
+}
 
-	ro.Days, err = getDays(year, n)
+// eventID returns the ID of the event found at n. If the ID can not be found in
+// the HTML, it is extracted from the URL url of the event. An empty string is
+// returned if there is no ID.
+func (w *walker) eventID(n *html.Node, url string) string {
+	if w.o.selectors.id != nil {
+		idnode := w.o.selectors.id.first(n)
+		if idnode != nil {
+			id := strings.TrimSpace(getAttributeValue(idnode.Attr, w.o.selectors.idAttribute))
+			if id != "" {
+				return id
+			}
+		}
+	}
+
+	if w.o.selectors.idPattern != nil {
+		m := w.o.selectors.idPattern.FindStringSubmatch(url)
+		if m != nil {
+			return m[1]
+		}
+	}
+
+	return ""
+}
+
+// report handles the problem err. In strict mode err is returned to abort the
+// walk. In lenient mode err is added to the warnings of the running order and
+// nil is returned.
+func (w *walker) report(err *ParseError) error {
+	if !w.o.lenient {
+		return err
+	}
+
+	w.ro.Warnings = append(w.ro.Warnings, err)
+	return nil
+}
+
+// parseDocument walks the HTML document n and returns the running order found
+// using the settings in o. If no year is set in o, it is inferred from n.
+func parseDocument(n *html.Node, o *options) (*RunningOrder, error) {
+	year := o.year
+	if year == 0 {
+		year = inferYear(n, o)
+	}
+
+	w := &walker{
+		o:     o,
+		ro:    &RunningOrder{Days: []*Day{}, Year: year, Schema: o.schema},
+		years: &yearTracker{year: year},
+	}
+
+	err := w.walk(n)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, d := range ro.Days {
-		d.Stages, err = getStages(d.node)
-		if err != nil {
-			return nil, err
-		}
+	if o.schema >= SchemaV2 {
+		addISOTimes(w.ro, o.location)
+	}
 
-		dd := time.Unix(d.TimeStamps.Start, 0).In(timezone)
-		for _, s := range d.Stages {
-			s.Events, err = getEvents(s.node, dd)
-			if err != nil {
-				return nil, err
-			}
+	return w.ro, nil
+}
+
+// ParseRunningOrder parses the HTML running order in r and returns a fully
+// populated RunningOrder. year is the year in which the festival takes place.
+// If year is 0, it is inferred from the running order (see Year).
+//
+// If the running order can not be parsed, the returned error is a *ParseError.
+// Day labels whose weekday does not match the date in year and events that are
+// not inside the span of their day do not make parsing fail; they are listed in
+// the Warnings of the returned RunningOrder.
+//
+// ParseRunningOrder is a shorthand for ParseRunningOrderWithOptions with the
+// Year option.
+func ParseRunningOrder(year int, r io.Reader) (*RunningOrder, error) {
+	return ParseRunningOrderWithOptions(r, Year(year))
+}
+
+// ParseRunningOrderLenient works like ParseRunningOrder, but does not give up
+// if parts of the running order can not be parsed. Days, stages and events
+// that can not be parsed are skipped or only partially filled. The problems
+// encountered are collected in the Warnings of the returned RunningOrder.
+//
+// An error is only returned if the HTML itself can not be read.
+//
+// ParseRunningOrderLenient is a shorthand for ParseRunningOrderWithOptions with
+// the Year and Lenient options.
+func ParseRunningOrderLenient(year int, r io.Reader) (*RunningOrder, error) {
+	return ParseRunningOrderWithOptions(r, Year(year), Lenient(true))
+}
+
+// parseRunningOrder parses the HTML running order in r using the settings in
+// o.
+//
+// parseRunningOrder never panics: any input yields either a RunningOrder or an
+// error. The parser itself is written not to panic (see FuzzParseDocument), but
+// as the running order is parsed on every HTTP request, any panic that slips
+// through is converted to a *ParseError of kind KindInternal as a last line of
+// defense.
+func parseRunningOrder(r io.Reader, o *options) (ro *RunningOrder, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			ro = nil
+			err = &ParseError{Kind: KindInternal, Err: fmt.Errorf("internal error: %v", p)}
 		}
+	}()
+
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	n, err := html.Parse(r)
+	if err != nil {
+		return nil, err
 	}
 
-	return ro, nil
+	return parseDocument(n, o)
 }
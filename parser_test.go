@@ -7,11 +7,12 @@
 package mdjson
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
-	"time"
 
 	"golang.org/x/net/html"
 )
@@ -51,24 +52,155 @@ func htmlParseFile(s string) (*html.Node, error) {
 	return n, nil
 }
 
-func TestGetDaysEmpty(t *testing.T) {
-	_, err := getDays(2016, failRootNode)
-	if err == nil {
-		t.Error("getDays(nil) returns no error")
+var parseDocumentFailTests = []struct {
+	name     string
+	html     string
+	expected ErrorKind
+}{
+	{
+		"day",
+		"<div class='lineup_day'></div>",
+		KindDay,
+	},
+	{
+		"stage",
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.</div></div></div>",
+		KindStage,
+	},
+	{
+		"event",
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+			"<div class='band_lineup'><span class='title'>Doro</span></div></div></div>",
+		KindEvent,
+	},
+	{
+		"day_timestamp",
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Someday 25. 07.<span>Main</span></div></div></div>",
+		KindTimeStamp,
+	},
+	{
+		"event_timestamp",
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+			"<div class='band_lineup'><span class='time'>soon - 22:00</span><span class='title'>Doro</span></div></div></div>",
+		KindTimeStamp,
+	},
+	{
+		"event_timestamp_no_length",
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+			"<div class='band_lineup'><span class='time'>22:00 - 22:00</span><span class='title'>Doro</span></div></div></div>",
+		KindTimeStamp,
+	},
+}
+
+func TestParseDocumentFail(t *testing.T) {
+	for _, test := range parseDocumentFailTests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parseDocument(n, newOptions(Year(2017)))
+			if err == nil {
+				t.Fatal("parseDocument returns no error")
+			}
+
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("unexpected error type: %T", err)
+			}
+			if pe.Kind != test.expected {
+				t.Errorf("unexpected kind; is %q; expected %q", pe.Kind, test.expected)
+			}
+		})
 	}
 }
 
-func TestGetStagesEmpty(t *testing.T) {
-	_, err := getStages(failRootNode)
-	if err == nil {
-		t.Error("getStages(nil) returns no error")
+func TestParseDocumentFailLenient(t *testing.T) {
+	for _, test := range parseDocumentFailTests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := parseDocument(n, newOptions(Year(2017), Lenient(true)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			t.Run("check warnings", compareWarningKinds(ro.Warnings, test.expected))
+		})
 	}
 }
 
-func TestGetEventsEmpty(t *testing.T) {
-	_, err := getEvents(failRootNode, time.Date(2017, 07, 23, 0, 0, 0, 0, timezone))
+func TestParseDocumentOutsideDay(t *testing.T) {
+	ts := []struct {
+		name string
+		time string
+	}{
+		{"ends_after_day", "23:30 - 10:15"},
+		{"after_midnight_ends_after_day", "08:30 - 10:30"},
+		{"crosses_cutoff", "09:00 - 11:00"},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(
+				"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+					"<div class='band_lineup'><span class='time'>" + test.time + "</span><span class='title'>Doro</span></div></div></div>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := parseDocument(n, newOptions(Year(2017)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			t.Run("check warnings", compareWarningKinds(ro.Warnings, KindTimeStamp))
+
+			e := ro.Days[0].Stages[0].Events[0]
+			if e.TimeStamps == nil {
+				t.Fatal("event has no timestamps")
+			}
+
+			is := Validate(ro)
+			if len(is) != 1 || is[0].Check != CheckOutsideDay {
+				t.Errorf("unexpected issues: %v", is)
+			}
+		})
+	}
+}
+
+func TestParseDocumentFailFile(t *testing.T) {
+	_, err := parseDocument(failRootNode, newOptions(Year(2016)))
 	if err == nil {
-		t.Error("getEvents(nil) returns no error")
+		t.Error("parseDocument returns no error")
+	}
+
+	ro, err := parseDocument(failRootNode, newOptions(Year(2016), Lenient(true)))
+	if err != nil {
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
+	}
+
+	if len(ro.Days) != 0 {
+		t.Errorf("unexpected number of days found; is %d; expected 0", len(ro.Days))
+	}
+	t.Run("check warnings", compareWarningKinds(ro.Warnings, KindDay))
+}
+
+func compareWarningKinds(is []*ParseError, expected ...ErrorKind) func(*testing.T) {
+	return func(t *testing.T) {
+		if len(is) != len(expected) {
+			t.Fatalf("unexpected number of warnings; is %d; expected %d", len(is), len(expected))
+		}
+
+		for i, w := range is {
+			if w.Kind != expected[i] {
+				t.Errorf("unexpected kind of warning %d; is %q; expected %q", i, w.Kind, expected[i])
+			}
+		}
 	}
 }
 
@@ -77,19 +209,20 @@ type testDay struct {
 	TimeStamps *TimeStamps
 }
 
-func TestGetDays(t *testing.T) {
+func TestParseDocumentDays(t *testing.T) {
 	year := 2017
 
 	expected := []testDay{
-		{"Saturday 22.07.", &TimeStamps{1500674400, 1500760800}},
-		{"Tuesday 25.07.", &TimeStamps{1500933600, 1501020000}},
-		{"Wednesday 26.07.", &TimeStamps{1501020000, 1501106400}},
+		{"Saturday 22.07.", &TimeStamps{Start: 1500674400, End: 1500760800}},
+		{"Tuesday 25.07.", &TimeStamps{Start: 1500933600, End: 1501020000}},
+		{"Wednesday 26.07.", &TimeStamps{Start: 1501020000, End: 1501106400}},
 	}
 
-	is, err := getDays(year, sampleRootNode)
+	ro, err := parseDocument(sampleRootNode, newOptions(Year(year)))
 	if err != nil {
-		t.Fatalf("getDays returned an unexpected error: %v", err)
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
 	}
+	is := ro.Days
 
 	for i, isDay := range is {
 		if i >= len(expected) {
@@ -110,17 +243,22 @@ func TestGetDays(t *testing.T) {
 	}
 }
 
-func TestGetStages(t *testing.T) {
+func TestParseDocumentStages(t *testing.T) {
 	expected := []string{
-		strings.Title("Newforces stage"),
-		strings.Title("Ian Fraser “Lemmy” Kilmister stage"),
-		strings.Title("Boško Bursać Stage"),
-		strings.Title("Ian Fraser “Lemmy” Kilmister stage"),
+		"Newforces Stage",
+		"Ian Fraser “Lemmy” Kilmister Stage",
+		"Boško Bursać Stage",
+		"Ian Fraser “Lemmy” Kilmister Stage",
 	}
 
-	is, err := getStages(sampleRootNode)
+	ro, err := parseDocument(sampleRootNode, newOptions(Year(2017)))
 	if err != nil {
-		t.Fatalf("getStages returned an unexpected error: %v", err)
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
+	}
+
+	is := []*Stage{}
+	for _, d := range ro.Days {
+		is = append(is, d.Stages...)
 	}
 
 	for i, isStage := range is {
@@ -141,57 +279,76 @@ func TestGetStages(t *testing.T) {
 }
 
 type testEvent struct {
-	Time       string
-	TimeStamps *TimeStamps
-	Label      string
-	URL        string
+	Time          string
+	TimeStamps    *TimeStamps
+	Label         string
+	OriginalLabel string
+	URL           string
+	ID            string
 }
 
-func TestGetEvents(t *testing.T) {
-	day := time.Date(2017, 07, 22, 0, 0, 0, 0, timezone)
-
+func TestParseDocumentEvents(t *testing.T) {
 	expected := []testEvent{
 		{
 			"-",
 			nil,
-			strings.Title("Tytus"),
+			"Tytus",
+			"Tytus",
 			"http://www.metaldays.net/b613/tytus",
+			"613",
 		},
 		{
 			"-",
 			nil,
-			strings.Title("Turbowarrior of steel"),
+			"Turbowarrior Of Steel",
+			"Turbowarrior of steel",
 			"http://www.metaldays.net/b612/turbowarrior-of-steel",
+			"612",
 		},
 		{
 			"22:30 - 00:00",
-			&TimeStamps{1500755400, 1500760800},
-			strings.Title("Amon Amarth"),
+			&TimeStamps{Start: 1501014600, End: 1501020000},
+			"Amon Amarth",
+			"Amon Amarth",
 			"http://www.metaldays.net/b526/amon-amarth",
+			"526",
 		},
 		{
 			"20:45 - 22:00",
-			&TimeStamps{1500749100, 1500753600},
-			strings.Title("Katatonia"),
+			&TimeStamps{Start: 1501008300, End: 1501012800},
+			"Katatonia",
+			"Katatonia",
 			"http://www.metaldays.net/b531/katatonia",
+			"531",
 		},
 		{
 			"00:10 - 01:20",
-			&TimeStamps{1500761400, 1500765600},
-			strings.Title("Kadavar"),
+			&TimeStamps{Start: 1501020600, End: 1501024800},
+			"Kadavar",
+			"Kadavar",
 			"http://www.metaldays.net/b539/kadavar",
+			"539",
 		},
 		{
 			"22:30 - 00:00",
-			&TimeStamps{1500755400, 1500760800},
-			strings.Title("Doro"),
+			&TimeStamps{Start: 1501101000, End: 1501106400},
+			"Doro",
+			"Doro",
 			"http://www.metaldays.net/b529/doro",
+			"529",
 		},
 	}
 
-	is, err := getEvents(sampleRootNode, day)
+	ro, err := parseDocument(sampleRootNode, newOptions(Year(2017)))
 	if err != nil {
-		t.Fatalf("getEvents returned an unexpected error: %v", err)
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
+	}
+
+	is := []*Event{}
+	for _, d := range ro.Days {
+		for _, s := range d.Stages {
+			is = append(is, s.Events...)
+		}
 	}
 
 	for i, isEvent := range is {
@@ -212,10 +369,20 @@ func TestGetEvents(t *testing.T) {
 				i, isEvent.Label, expected[i].Label)
 		}
 
+		if isEvent.OriginalLabel != expected[i].OriginalLabel {
+			t.Errorf("Unexpected original label for event %d; is \"%s\"; expected \"%s\"",
+				i, isEvent.OriginalLabel, expected[i].OriginalLabel)
+		}
+
 		if isEvent.URL != expected[i].URL {
 			t.Errorf("Unexpected URL for event %d; is \"%s\"; expected \"%s\"",
 				i, isEvent.URL, expected[i].URL)
 		}
+
+		if isEvent.ID != expected[i].ID {
+			t.Errorf("Unexpected ID for event %d; is \"%s\"; expected \"%s\"",
+				i, isEvent.ID, expected[i].ID)
+		}
 	}
 
 	if len(is) != len(expected) {
@@ -228,87 +395,118 @@ func TestParseRunningOrder(t *testing.T) {
 	year := 2016
 
 	expected := RunningOrder{
-		[]*Day{
+		Days: []*Day{
 			{
 				"Saturday 22.07.",
 				[]*Stage{
 					{
-						strings.Title("Newforces stage"),
+						"Newforces Stage",
 						[]*Event{
 							{
 								"-",
 								nil,
-								strings.Title("Tytus"),
+								"Tytus",
+								"Tytus",
 								"http://www.metaldays.net/b613/tytus",
+								"613",
 							},
 							{
 								"-",
 								nil,
-								strings.Title("Turbowarrior of steel"),
+								"Turbowarrior Of Steel",
+								"Turbowarrior of steel",
 								"http://www.metaldays.net/b612/turbowarrior-of-steel",
+								"612",
 							},
 						},
-						nil,
+						StageKindSecond,
+						1,
 					},
 				},
-				&TimeStamps{1469138400, 1469224800},
-				nil,
+				&TimeStamps{Start: 1469138400, End: 1469224800},
+				&TimeStamps{Start: 1469174400, End: 1469260800},
+				true,
+				[]string{
+					"Running order is preliminary and changes are still possible!",
+					"For detailed timeline with overlaps between stages, please print the pdf file (button below).",
+				},
 			},
 			{
 				"Tuesday 25.07.",
 				[]*Stage{
 					{
-						strings.Title("Ian Fraser “Lemmy” Kilmister stage"),
+						"Ian Fraser “Lemmy” Kilmister Stage",
 						[]*Event{
 							{
 								"22:30 - 00:00",
-								&TimeStamps{1469478600, 1469484000},
-								strings.Title("Amon Amarth"),
+								&TimeStamps{Start: 1469478600, End: 1469484000},
+								"Amon Amarth",
+								"Amon Amarth",
 								"http://www.metaldays.net/b526/amon-amarth",
+								"526",
 							},
 							{
 								"20:45 - 22:00",
-								&TimeStamps{1469472300, 1469476800},
-								strings.Title("Katatonia"),
+								&TimeStamps{Start: 1469472300, End: 1469476800},
+								"Katatonia",
+								"Katatonia",
 								"http://www.metaldays.net/b531/katatonia",
+								"531",
 							},
 						},
-						nil,
+						StageKindMain,
+						1,
 					},
 					{
-						strings.Title("Boško Bursać Stage"),
+						"Boško Bursać Stage",
 						[]*Event{
 							{
 								"00:10 - 01:20",
-								&TimeStamps{1469484600, 1469488800},
-								strings.Title("Kadavar"),
+								&TimeStamps{Start: 1469484600, End: 1469488800},
+								"Kadavar",
+								"Kadavar",
 								"http://www.metaldays.net/b539/kadavar",
+								"539",
 							},
 						},
-						nil,
+						StageKindSecond,
+						2,
 					},
 				},
-				&TimeStamps{1469397600, 1469484000},
-				nil,
+				&TimeStamps{Start: 1469397600, End: 1469484000},
+				&TimeStamps{Start: 1469433600, End: 1469520000},
+				true,
+				[]string{
+					"Running order is preliminary and changes are still possible!",
+					"For detailed timeline with overlaps between stages, please print the pdf file (button below).",
+				},
 			},
 			{
 				"Wednesday 26.07.",
 				[]*Stage{
 					{
-						strings.Title("Ian Fraser “Lemmy” Kilmister stage"),
+						"Ian Fraser “Lemmy” Kilmister Stage",
 						[]*Event{
 							{
 								"22:30 - 00:00",
-								&TimeStamps{1469565000, 1469570400},
-								strings.Title("Doro"),
+								&TimeStamps{Start: 1469565000, End: 1469570400},
+								"Doro",
+								"Doro",
 								"http://www.metaldays.net/b529/doro",
+								"529",
 							},
 						},
-						nil,
+						StageKindMain,
+						1,
 					},
 				},
-				&TimeStamps{1469484000, 1469570400},
-				nil,
+				&TimeStamps{Start: 1469484000, End: 1469570400},
+				&TimeStamps{Start: 1469520000, End: 1469606400},
+				true,
+				[]string{
+					"Running order is preliminary and changes are still possible!",
+					"For detailed timeline with overlaps between stages, please print the pdf file (button below).",
+				},
 			},
 		},
 	}
@@ -323,6 +521,11 @@ func TestParseRunningOrder(t *testing.T) {
 		t.Fatalf("ParseRunningOrder returns unexpected error: %v", err)
 	}
 
+	if ro.Year != year {
+		t.Errorf("unexpected year; is %d; expected %d", ro.Year, year)
+	}
+	t.Run("check year conflicts", compareWarningKinds(ro.Warnings, KindYear, KindYear, KindYear))
+
 	if len(ro.Days) != len(expected.Days) {
 		t.Errorf("unexpected number of days; is %d; expected %d",
 			len(ro.Days), len(expected.Days))
@@ -341,6 +544,9 @@ func TestParseRunningOrder(t *testing.T) {
 		t.Run(fmt.Sprintf("check timestamps for day %d", d),
 			compareTimeStampsPointers(day.TimeStamps, expected.Days[d].TimeStamps))
 
+		t.Run(fmt.Sprintf("check span for day %d", d),
+			compareTimeStampsPointers(day.Span, expected.Days[d].Span))
+
 		if len(day.Stages) != len(expected.Days[d].Stages) {
 			t.Errorf("unexpected number of stages for day %d; is %d; expected %d",
 				d, len(day.Stages), len(expected.Days[d].Stages))
@@ -356,6 +562,16 @@ func TestParseRunningOrder(t *testing.T) {
 				continue
 			}
 
+			if stage.Kind != expected.Days[d].Stages[s].Kind {
+				t.Errorf("unexpected kind for stage %d; is %q; expected %q",
+					s, stage.Kind, expected.Days[d].Stages[s].Kind)
+			}
+
+			if stage.Position != expected.Days[d].Stages[s].Position {
+				t.Errorf("unexpected position for stage %d; is %d; expected %d",
+					s, stage.Position, expected.Days[d].Stages[s].Position)
+			}
+
 			if len(stage.Events) != len(expected.Days[d].Stages[s].Events) {
 				t.Errorf("unexpected number of events for day %d, stage %d; is %d; expected %d",
 					d, s, len(stage.Events), len(expected.Days[d].Stages[s].Events))
@@ -380,7 +596,304 @@ func TestParseRunningOrder(t *testing.T) {
 						e, event.URL,
 						expected.Days[d].Stages[s].Events[e].URL)
 				}
+
+				if event.ID != expected.Days[d].Stages[s].Events[e].ID {
+					t.Errorf("unexpected id for event %d; is \"%s\"; expected \"%s\"",
+						e, event.ID,
+						expected.Days[d].Stages[s].Events[e].ID)
+				}
 			}
 		}
 	}
 }
+
+func TestParseRunningOrderBroken(t *testing.T) {
+	f, err := os.Open("./testdata/broken.html")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	_, err = ParseRunningOrder(2017, f)
+	if err == nil {
+		t.Error("ParseRunningOrder returns no error")
+	}
+}
+
+func TestParseRunningOrderLenient(t *testing.T) {
+	f, err := os.Open("./testdata/broken.html")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	ro, err := ParseRunningOrderLenient(2017, f)
+	if err != nil {
+		t.Fatalf("ParseRunningOrderLenient returns unexpected error: %v", err)
+	}
+
+	t.Run("check warnings", compareWarningKinds(ro.Warnings,
+		KindEvent, KindTimeStamp, KindStage, KindTimeStamp, KindDay))
+
+	expected := []struct {
+		label      string
+		timestamps *TimeStamps
+		events     []testEvent
+	}{
+		{
+			"Tuesday 25.07.",
+			&TimeStamps{Start: 1500933600, End: 1501020000},
+			[]testEvent{
+				{"22:30 - 00:00", &TimeStamps{Start: 1501014600, End: 1501020000}, "Amon Amarth", "Amon Amarth", "", ""},
+				{"20:45 - 2x:00", nil, "Katatonia", "Katatonia", "", ""},
+			},
+		},
+		{
+			"Someday 26.07.",
+			nil,
+			[]testEvent{
+				{"22:30 - 00:00", nil, "Doro", "Doro", "", ""},
+			},
+		},
+	}
+
+	if len(ro.Days) != len(expected) {
+		t.Fatalf("unexpected number of days; is %d; expected %d", len(ro.Days), len(expected))
+	}
+	for d, day := range ro.Days {
+		if day.Label != expected[d].label {
+			t.Errorf("unexpected label for day %d; is %q; expected %q", d, day.Label, expected[d].label)
+		}
+
+		t.Run(fmt.Sprintf("check timestamps for day %d", d),
+			compareTimeStampsPointers(day.TimeStamps, expected[d].timestamps))
+
+		if len(day.Stages) != 1 {
+			t.Fatalf("unexpected number of stages for day %d; is %d; expected 1", d, len(day.Stages))
+		}
+
+		events := day.Stages[0].Events
+		if len(events) != len(expected[d].events) {
+			t.Fatalf("unexpected number of events for day %d; is %d; expected %d",
+				d, len(events), len(expected[d].events))
+		}
+		for e, event := range events {
+			if event.Label != expected[d].events[e].Label {
+				t.Errorf("unexpected label for event %d %d; is %q; expected %q",
+					d, e, event.Label, expected[d].events[e].Label)
+			}
+
+			t.Run(fmt.Sprintf("check timestamps for event %d %d", d, e),
+				compareTimeStampsPointers(event.TimeStamps, expected[d].events[e].TimeStamps))
+		}
+	}
+}
+
+func BenchmarkParseRunningOrder(b *testing.B) {
+	data, err := os.ReadFile("./testdata/sample.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := ParseRunningOrder(2017, bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseDocument(b *testing.B) {
+	o := newOptions(Year(2017))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := parseDocument(sampleRootNode, o)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseDocumentEventIDs(t *testing.T) {
+	ts := []struct {
+		name     string
+		event    string
+		layout   *Layout
+		expected string
+	}{
+		{
+			"checkbox",
+			"<div class='band_lineup' href='http://www.metaldays.net/b1/foo'><input class='band-selection' value=' 2 '>",
+			DefaultLayout(),
+			"2",
+		},
+		{
+			"href",
+			"<div class='band_lineup' href='http://www.metaldays.net/b1/foo'>",
+			DefaultLayout(),
+			"1",
+		},
+		{
+			"empty_checkbox",
+			"<div class='band_lineup' href='http://www.metaldays.net/b1/foo'><input class='band-selection' value=''>",
+			DefaultLayout(),
+			"1",
+		},
+		{
+			"none",
+			"<div class='band_lineup' href='http://www.metaldays.net/foo'>",
+			DefaultLayout(),
+			"",
+		},
+		{
+			"without_pattern",
+			"<div class='band_lineup' href='http://www.metaldays.net/b1/foo'>",
+			func() *Layout {
+				l := DefaultLayout()
+				l.ID = ""
+				l.IDPattern = ""
+				return l
+			}(),
+			"",
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(
+				"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div>" +
+					test.event + "<span class='time'>-</span><span class='title'>Foo</span></div></div></div>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := parseDocument(n, newOptions(Year(2017), SiteLayout(test.layout)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			is := ro.Days[0].Stages[0].Events[0].ID
+			if is != test.expected {
+				t.Errorf("unexpected ID; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseDocumentStageKinds(t *testing.T) {
+	ts := []struct {
+		name     string
+		stage    string
+		expected string
+	}{
+		{"stage_class", "<div class='lineup_stage main_stage'><div class='l-stage'>", StageKindMain},
+		{"descendant_class", "<div class='lineup_stage'><div class='l-stage l-second'>", StageKindSecond},
+		{"stage_class_first", "<div class='lineup_stage second_stage'><div class='l-stage l-main'>", StageKindSecond},
+		{"unknown_class", "<div class='lineup_stage third_stage'><div class='l-stage l-third'>", ""},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(
+				"<div class='lineup_day'>" + test.stage + "Tuesday 25. 07.<span>Main</span></div></div></div>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := parseDocument(n, newOptions(Year(2017)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			if is := ro.Days[0].Stages[0].Kind; is != test.expected {
+				t.Errorf("unexpected kind; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseDocumentAnnouncements(t *testing.T) {
+	ts := []struct {
+		name          string
+		notices       string
+		layout        *Layout
+		announcements []string
+		preliminary   bool
+	}{
+		{
+			"preliminary",
+			"<div class='order_info'><span>*</span>Running order is preliminary!<br><span>See the pdf.</span></div>",
+			DefaultLayout(),
+			[]string{"Running order is preliminary!", "See the pdf."},
+			true,
+		},
+		{
+			"final",
+			"<div class='order_info'>* Doors open at 10:00</div><div class='order_info'><p>Bring water</p></div>",
+			DefaultLayout(),
+			[]string{"Doors open at 10:00", "Bring water"},
+			false,
+		},
+		{
+			"none",
+			"",
+			DefaultLayout(),
+			nil,
+			false,
+		},
+		{
+			"without_notice",
+			"<div class='order_info'>Running order is preliminary!</div>",
+			func() *Layout {
+				l := DefaultLayout()
+				l.Notice = ""
+				return l
+			}(),
+			nil,
+			false,
+		},
+		{
+			"without_pattern",
+			"<div class='order_info'>Running order is preliminary!</div>",
+			func() *Layout {
+				l := DefaultLayout()
+				l.PreliminaryPattern = ""
+				return l
+			}(),
+			[]string{"Running order is preliminary!"},
+			false,
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(
+				"<div class='lineup_day'>" + test.notices + "<div class='lineup_stage'><div class='l-stage'>Tuesday 25. 07.<span>Main</span></div></div></div>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := parseDocument(n, newOptions(Year(2017), SiteLayout(test.layout)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			d := ro.Days[0]
+			if !reflect.DeepEqual(d.Announcements, test.announcements) {
+				t.Errorf("unexpected announcements; is %q; expected %q", d.Announcements, test.announcements)
+			}
+			if d.Preliminary != test.preliminary {
+				t.Errorf("unexpected preliminary flag of day; is %t; expected %t", d.Preliminary, test.preliminary)
+			}
+			if ro.Preliminary != test.preliminary {
+				t.Errorf("unexpected preliminary flag of running order; is %t; expected %t", ro.Preliminary, test.preliminary)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The versions of the output schema of a RunningOrder.
+const (
+	// SchemaV1 contains the timestamps as unix seconds only.
+	SchemaV1 = 1
+
+	// SchemaV2 additionally contains the start and end of TimeStamps as
+	// RFC 3339 strings in the time zone of the festival and their duration
+	// as ISO 8601 duration.
+	SchemaV2 = 2
+
+	// LatestSchema is the latest version of the output schema.
+	LatestSchema = SchemaV2
+)
+
+// Schema sets the version of the output schema. The default is SchemaV1, so
+// existing consumers of the running order keep working. Versions below
+// SchemaV1 or above LatestSchema are rejected by ParseRunningOrderWithOptions.
+func Schema(version int) Option {
+	return func(o *options) {
+		o.schema = version
+	}
+}
+
+// addISOTimes adds the RFC 3339 start and end times and the durations in the
+// time.Location loc to all TimeStamps of ro.
+func addISOTimes(ro *RunningOrder, loc *time.Location) {
+	for _, d := range ro.Days {
+		d.TimeStamps.addISOTimes(loc)
+		d.Span.addISOTimes(loc)
+
+		for _, s := range d.Stages {
+			for _, e := range s.Events {
+				e.TimeStamps.addISOTimes(loc)
+			}
+		}
+	}
+}
+
+// addISOTimes sets StartTime, EndTime and Duration of ts according to Start
+// and End. If End is unknown, only StartTime is set. ts may be nil.
+func (ts *TimeStamps) addISOTimes(loc *time.Location) {
+	if ts == nil {
+		return
+	}
+
+	start := time.Unix(ts.Start, 0).In(loc)
+	ts.StartTime = start.Format(time.RFC3339)
+
+	if ts.End == 0 {
+		return
+	}
+
+	end := time.Unix(ts.End, 0).In(loc)
+	ts.EndTime = end.Format(time.RFC3339)
+	ts.Duration = formatISODuration(end.Sub(start))
+}
+
+// formatISODuration formats d as ISO 8601 duration, e.g. "PT1H15M". Fractions
+// of seconds are dropped.
+func formatISODuration(d time.Duration) string {
+	var b strings.Builder
+
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("PT")
+
+	h := d / time.Hour
+	m := d % time.Hour / time.Minute
+	s := d % time.Minute / time.Second
+
+	if h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if s > 0 || (h == 0 && m == 0) {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+
+	return b.String()
+}
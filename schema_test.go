@@ -0,0 +1,117 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFormatISODuration(t *testing.T) {
+	ts := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "PT0S"},
+		{45 * time.Minute, "PT45M"},
+		{time.Hour + 10*time.Minute, "PT1H10M"},
+		{24 * time.Hour, "PT24H"},
+		{90*time.Second + 500*time.Millisecond, "PT1M30S"},
+		{-time.Hour, "-PT1H"},
+	}
+
+	for _, test := range ts {
+		t.Run(test.expected, func(t *testing.T) {
+			if is := formatISODuration(test.d); is != test.expected {
+				t.Errorf("unexpected duration; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestTimeStampsAddISOTimes(t *testing.T) {
+	ts := []struct {
+		timestamps *TimeStamps
+		loc        *time.Location
+		expected   *TimeStamps
+	}{
+		{
+			&TimeStamps{Start: 1501020600, End: 1501024800},
+			defaultLocation,
+			&TimeStamps{1501020600, 1501024800, "2017-07-26T00:10:00+02:00", "2017-07-26T01:20:00+02:00", "PT1H10M"},
+		},
+		{
+			&TimeStamps{Start: 1501020600, End: 1501024800},
+			time.UTC,
+			&TimeStamps{1501020600, 1501024800, "2017-07-25T22:10:00Z", "2017-07-25T23:20:00Z", "PT1H10M"},
+		},
+		{
+			&TimeStamps{Start: 1501020600},
+			defaultLocation,
+			&TimeStamps{1501020600, 0, "2017-07-26T00:10:00+02:00", "", ""},
+		},
+		{
+			// The day the clocks are changed to summer time is
+			// only 23 hours long.
+			&TimeStamps{Start: 1490482800, End: 1490565600},
+			defaultLocation,
+			&TimeStamps{1490482800, 1490565600, "2017-03-26T00:00:00+01:00", "2017-03-27T00:00:00+02:00", "PT23H"},
+		},
+		{nil, defaultLocation, nil},
+	}
+
+	for i, test := range ts {
+		t.Run(fmt.Sprintf("test %d", i), func(t *testing.T) {
+			test.timestamps.addISOTimes(test.loc)
+			compareTimeStampsPointers(test.timestamps, test.expected)(t)
+		})
+	}
+}
+
+func TestParseRunningOrderWithSchema(t *testing.T) {
+	ts := []struct {
+		schema   int
+		valid    bool
+		expected *TimeStamps
+	}{
+		{SchemaV1, true, &TimeStamps{Start: 1501020600, End: 1501024800}},
+		{SchemaV2, true, &TimeStamps{1501020600, 1501024800, "2017-07-26T00:10:00+02:00", "2017-07-26T01:20:00+02:00", "PT1H10M"}},
+		{0, false, nil},
+		{LatestSchema + 1, false, nil},
+	}
+
+	for _, test := range ts {
+		t.Run(fmt.Sprintf("schema %d", test.schema), func(t *testing.T) {
+			f, err := os.Open("./testdata/sample.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ro, err := ParseRunningOrderWithOptions(f, Year(2017), Schema(test.schema))
+			if (err == nil) != test.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.valid {
+				return
+			}
+
+			if ro.Schema != test.schema {
+				t.Errorf("unexpected schema; is %d; expected %d", ro.Schema, test.schema)
+			}
+
+			day := ro.Days[1]
+			t.Run("check timestamps", compareTimeStampsPointers(day.Stages[1].Events[0].TimeStamps, test.expected))
+
+			if test.schema >= SchemaV2 && (day.TimeStamps.StartTime == "" || day.Span.StartTime == "") {
+				t.Error("day timestamps lack RFC 3339 times")
+			}
+		})
+	}
+}
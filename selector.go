@@ -0,0 +1,378 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// A selector is a compiled group of CSS selectors. It matches a node if any
+// of its alternatives matches.
+//
+// Only a subset of CSS is supported: type selectors, the universal selector,
+// id and class selectors, the attribute selectors [attr], [attr=value] and
+// [attr~=value] and the descendant and child combinators.
+type selector []complexSelector
+
+// A complexSelector is a sequence of compound selectors separated by
+// combinators.
+type complexSelector []selectorStep
+
+// A selectorStep is a compound selector together with the combinator that
+// relates it to the previous step.
+type selectorStep struct {
+	// combinator is either ' ' (descendant) or '>' (child). It is ignored
+	// for the first step.
+	combinator byte
+
+	compound compoundSelector
+}
+
+// A compoundSelector is a sequence of simple selectors that all have to match
+// the same element.
+type compoundSelector struct {
+	// tag is the tag name of the element. An empty tag matches any
+	// element.
+	tag     string
+	id      string
+	classes []string
+	attrs   []attributeSelector
+}
+
+// An attributeSelector matches elements by their attributes.
+type attributeSelector struct {
+	key string
+
+	// op is 0 if the attribute has to exist, '=' if its value has to
+	// equal val and '~' if val has to be one of its whitespace separated
+	// values.
+	op  byte
+	val string
+}
+
+// compileSelector compiles the CSS selector group s.
+func compileSelector(s string) (selector, error) {
+	p := &selectorParser{s: s}
+
+	sel, err := p.parseGroup()
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %v", s, err)
+	}
+
+	return sel, nil
+}
+
+// match returns true if the element n is matched by s.
+func (s selector) match(n *html.Node) bool {
+	for _, c := range s {
+		if c.matchAt(n, len(c)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// first returns the first node below n (in document order, excluding n itself)
+// that is matched by s. If there is no such node, nil is returned.
+func (s selector) first(n *html.Node) *html.Node {
+	if n == nil {
+		return nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if s.match(c) {
+			return c
+		}
+
+		if f := s.first(c); f != nil {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// matchAt returns true if the steps up to and including step i of c match the
+// element n and its ancestors.
+func (c complexSelector) matchAt(n *html.Node, i int) bool {
+	if !c[i].compound.match(n) {
+		return false
+	}
+
+	if i == 0 {
+		return true
+	}
+
+	if c[i].combinator == '>' {
+		return n.Parent != nil && c.matchAt(n.Parent, i-1)
+	}
+
+	for p := n.Parent; p != nil; p = p.Parent {
+		if c.matchAt(p, i-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match returns true if the element n is matched by all simple selectors of c.
+func (c compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+
+	if c.id != "" && getAttributeValue(n.Attr, "id") != c.id {
+		return false
+	}
+
+	for _, cl := range c.classes {
+		if !hasAttributeValue(n.Attr, "class", cl) {
+			return false
+		}
+	}
+
+	for _, a := range c.attrs {
+		if !a.match(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match returns true if the element n is matched by a.
+func (a attributeSelector) match(n *html.Node) bool {
+	for _, at := range n.Attr {
+		if at.Key != a.key {
+			continue
+		}
+
+		switch a.op {
+		case '=':
+			return at.Val == a.val
+		case '~':
+			return hasAttributeValue(n.Attr, a.key, a.val)
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectorParser is a simple recursive descent parser for selector groups.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+// parseGroup parses a comma separated list of complex selectors.
+func (p *selectorParser) parseGroup() (selector, error) {
+	var sel selector
+
+	for {
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, c)
+
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return sel, nil
+		}
+
+		if p.s[p.pos] != ',' {
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+		}
+		p.pos++
+	}
+}
+
+// parseComplex parses a sequence of compound selectors separated by
+// combinators.
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var c complexSelector
+
+	p.skipSpace()
+	combinator := byte(' ')
+	for {
+		cs, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, selectorStep{combinator, cs})
+
+		space := p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] == ',' {
+			return c, nil
+		}
+
+		switch {
+		case p.s[p.pos] == '>':
+			combinator = '>'
+			p.pos++
+			p.skipSpace()
+		case space:
+			combinator = ' '
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+		}
+	}
+}
+
+// parseCompound parses a sequence of simple selectors.
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] == '*' {
+		p.pos++
+	} else {
+		c.tag = strings.ToLower(p.parseIdent())
+	}
+
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '#':
+			p.pos++
+			c.id = p.parseIdent()
+			if c.id == "" {
+				return c, fmt.Errorf("missing id at offset %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			cl := p.parseIdent()
+			if cl == "" {
+				return c, fmt.Errorf("missing class at offset %d", p.pos)
+			}
+			c.classes = append(c.classes, cl)
+		case '[':
+			p.pos++
+			a, err := p.parseAttribute()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+			}
+			return c, nil
+		}
+	}
+
+	if p.pos == start {
+		return c, fmt.Errorf("missing selector at offset %d", p.pos)
+	}
+
+	return c, nil
+}
+
+// parseAttribute parses an attribute selector. The opening bracket has already
+// been consumed.
+func (p *selectorParser) parseAttribute() (attributeSelector, error) {
+	var a attributeSelector
+
+	p.skipSpace()
+	a.key = strings.ToLower(p.parseIdent())
+	if a.key == "" {
+		return a, fmt.Errorf("missing attribute name at offset %d", p.pos)
+	}
+	p.skipSpace()
+
+	switch {
+	case strings.HasPrefix(p.s[p.pos:], "="):
+		a.op = '='
+		p.pos++
+	case strings.HasPrefix(p.s[p.pos:], "~="):
+		a.op = '~'
+		p.pos += 2
+	}
+
+	if a.op != 0 {
+		p.skipSpace()
+
+		var err error
+		a.val, err = p.parseValue()
+		if err != nil {
+			return a, err
+		}
+		p.skipSpace()
+	}
+
+	if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+		return a, fmt.Errorf("missing ']' at offset %d", p.pos)
+	}
+	p.pos++
+
+	return a, nil
+}
+
+// parseValue parses an identifier or a quoted string.
+func (p *selectorParser) parseValue() (string, error) {
+	if p.pos >= len(p.s) {
+		return "", fmt.Errorf("missing value at offset %d", p.pos)
+	}
+
+	q := p.s[p.pos]
+	if q != '"' && q != '\'' {
+		v := p.parseIdent()
+		if v == "" {
+			return "", fmt.Errorf("missing value at offset %d", p.pos)
+		}
+
+		return v, nil
+	}
+
+	end := strings.IndexByte(p.s[p.pos+1:], q)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at offset %d", p.pos)
+	}
+
+	v := p.s[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+
+	return v, nil
+}
+
+// parseIdent parses an identifier, which may be empty.
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.pos += size
+	}
+
+	return p.s[start:p.pos]
+}
+
+// skipSpace skips any whitespace and reports whether whitespace was found.
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+
+	for p.pos < len(p.s) && strings.IndexByte(" \t\n\r\f", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+
+	return p.pos > start
+}
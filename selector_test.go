@@ -0,0 +1,105 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorTestHTML = `
+<div id="outer" class="a b">
+  <p class="c" data-x="foo bar">one</p>
+  <section>
+    <p class="c" data-x="baz">two</p>
+  </section>
+  <span lang="sl">three</span>
+</div>`
+
+func TestSelectorFirst(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorTestHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := []struct {
+		selector string
+		expected string
+	}{
+		{"p", "one"},
+		{"P", "one"},
+		{"section p", "two"},
+		{"div > p", "one"},
+		{"section > p", "two"},
+		{"body > p", ""},
+		{"#outer .c", "one"},
+		{"div.a.b > section .c", "two"},
+		{"div.a.x p", ""},
+		{"[data-x=baz]", "two"},
+		{"[data-x='foo bar']", "one"},
+		{"p[data-x~=bar]", "one"},
+		{"p[data-x~=ba]", ""},
+		{"[lang]", "three"},
+		{"* > span", "three"},
+		{"em, span", "three"},
+		{"em", ""},
+	}
+
+	for _, test := range ts {
+		t.Run(test.selector, func(t *testing.T) {
+			sel, err := compileSelector(test.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			is := elementText(sel.first(doc))
+			if is != test.expected {
+				t.Errorf("unexpected match; expected %q; is %q", test.expected, is)
+			}
+		})
+	}
+}
+
+func TestCompileSelectorInvalid(t *testing.T) {
+	ts := []string{
+		"",
+		" ",
+		"p >",
+		"> p",
+		"p,",
+		"p.",
+		"p#",
+		"[",
+		"[x",
+		"[x=]",
+		"[x='y]",
+		"p + q",
+		"p:first-child",
+	}
+
+	for _, test := range ts {
+		t.Run(test, func(t *testing.T) {
+			_, err := compileSelector(test)
+			if err == nil {
+				t.Errorf("compileSelector(%q) returns no error", test)
+			}
+		})
+	}
+}
+
+func TestSelectorFirstNil(t *testing.T) {
+	sel, err := compileSelector("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if is := sel.first(nil); is != nil {
+		t.Errorf("first(nil) returns %v", is)
+	}
+}
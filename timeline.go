@@ -0,0 +1,307 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+const (
+	// DefaultMinuteHeight is the default height of a minute in the grid of
+	// a TimelineEncoder in pixels.
+	DefaultMinuteHeight = 2
+
+	// timelineOpenEnd is the length of the blocks of events without an end
+	// and without a following event on their stage, in seconds.
+	timelineOpenEnd = 60 * 60
+)
+
+// A TimelineEncoder writes running orders as a self-contained HTML page to an
+// output stream.
+//
+// Every day with timed events is rendered as a grid with a time axis and the
+// stages as columns. Every event is a block whose position and height are
+// proportional to its start and its length, so sets overlapping on different
+// stages are found side by side. Events without an end last until the next
+// event on their stage, but at most an hour; their blocks are marked as open.
+// Events without timestamps are listed below the grid of their day.
+type TimelineEncoder struct {
+	w      io.Writer
+	loc    *time.Location
+	minute int
+}
+
+// NewTimelineEncoder returns a new TimelineEncoder that writes to w. The time
+// zone defaults to Europe/Ljubljana, the height of a minute to
+// DefaultMinuteHeight.
+func NewTimelineEncoder(w io.Writer) *TimelineEncoder {
+	return &TimelineEncoder{w, defaultLocation, DefaultMinuteHeight}
+}
+
+// SetLocation sets the time.Location the time axis is given in. It should be
+// the location the running order has been parsed with. A nil loc is treated
+// as time.UTC.
+func (enc *TimelineEncoder) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	enc.loc = loc
+}
+
+// SetMinuteHeight sets the height of a minute in the grid in pixels. Values
+// smaller than 1 are treated as 1.
+func (enc *TimelineEncoder) SetMinuteHeight(px int) {
+	if px < 1 {
+		px = 1
+	}
+
+	enc.minute = px
+}
+
+// Encode writes the HTML page of ro to the stream.
+func (enc *TimelineEncoder) Encode(ro *RunningOrder) error {
+	p := timelinePage{Title: "Running Order"}
+	if ro.Year != 0 {
+		p.Title = fmt.Sprintf("Running Order %d", ro.Year)
+	}
+
+	for _, d := range ro.Days {
+		p.Days = append(p.Days, enc.day(d))
+	}
+
+	return timelineTemplate.Execute(enc.w, p)
+}
+
+// timelinePage is the data of timelineTemplate.
+type timelinePage struct {
+	Title string
+	Days  []*timelineDay
+}
+
+// A timelineDay is the grid of a day. The grid is empty if the day has no timed
+// events.
+type timelineDay struct {
+	Label         string
+	Preliminary   bool
+	Announcements []string
+
+	Height int
+	Hours  []timelineHour
+	Stages []*timelineStage
+
+	Unscheduled []timelineUnscheduled
+}
+
+// A timelineHour is a line of the time axis.
+type timelineHour struct {
+	Top   int
+	Label string
+}
+
+// A timelineStage is a column of the grid.
+type timelineStage struct {
+	Label  string
+	Kind   string
+	Blocks []timelineBlock
+}
+
+// A timelineBlock is an event in a column of the grid.
+type timelineBlock struct {
+	Top    int
+	Height int
+	Time   string
+	Label  string
+	URL    string
+	Open   bool
+}
+
+// A timelineUnscheduled is an event without timestamps.
+type timelineUnscheduled struct {
+	Stage string
+	Time  string
+	Label string
+	URL   string
+}
+
+// day returns the grid of d. The time axis spans the full hours containing the
+// timed events of d.
+func (enc *TimelineEncoder) day(d *Day) *timelineDay {
+	td := &timelineDay{
+		Label:         d.Label,
+		Preliminary:   d.Preliminary,
+		Announcements: d.Announcements,
+	}
+
+	var first, last int64
+	ok := false
+	for _, s := range d.Stages {
+		for _, e := range s.Events {
+			if e.TimeStamps == nil {
+				td.Unscheduled = append(td.Unscheduled, timelineUnscheduled{s.Label, e.Time, e.Label, e.URL})
+				continue
+			}
+
+			end, _ := timelineEnd(d, s, e)
+			if !ok || e.TimeStamps.Start < first {
+				first = e.TimeStamps.Start
+			}
+			if !ok || end > last {
+				last = end
+			}
+			ok = true
+		}
+	}
+
+	if !ok {
+		return td
+	}
+
+	start := enc.hour(first)
+	end := enc.hour(last)
+	if end.Unix() < last {
+		end = end.Add(time.Hour)
+	}
+
+	px := func(ts int64) int {
+		return int(ts-start.Unix()) / 60 * enc.minute
+	}
+
+	td.Height = px(end.Unix())
+	for h := start; !h.After(end); h = h.Add(time.Hour) {
+		td.Hours = append(td.Hours, timelineHour{px(h.Unix()), h.Format("15:04")})
+	}
+
+	for _, s := range d.Stages {
+		ts := &timelineStage{Label: s.Label, Kind: s.Kind}
+
+		for _, e := range s.Events {
+			if e.TimeStamps == nil {
+				continue
+			}
+
+			stop, open := timelineEnd(d, s, e)
+			ts.Blocks = append(ts.Blocks, timelineBlock{
+				Top:    px(e.TimeStamps.Start),
+				Height: px(stop) - px(e.TimeStamps.Start),
+				Time:   e.Time,
+				Label:  e.Label,
+				URL:    e.URL,
+				Open:   open,
+			})
+		}
+
+		if len(ts.Blocks) > 0 {
+			td.Stages = append(td.Stages, ts)
+		}
+	}
+
+	return td
+}
+
+// hour returns the beginning of the hour containing the unix timestamp ts in
+// enc.loc.
+func (enc *TimelineEncoder) hour(ts int64) time.Time {
+	t := time.Unix(ts, 0).In(enc.loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, enc.loc)
+}
+
+// timelineEnd returns the end of the block of the event e on stage s on day d
+// (see eventEnd). open is true if e has no end of its own; the block of such
+// an event lasts at most timelineOpenEnd seconds.
+func timelineEnd(d *Day, s *Stage, e *Event) (end int64, open bool) {
+	if e.TimeStamps.End != 0 {
+		return e.TimeStamps.End, false
+	}
+
+	end = eventEnd(d, s, e)
+	if end == 0 || end-e.TimeStamps.Start > timelineOpenEnd {
+		end = e.TimeStamps.Start + timelineOpenEnd
+	}
+
+	return end, true
+}
+
+// timelineTemplate renders a timelinePage. The page does not reference any
+// external resources, so it can be saved and viewed offline.
+var timelineTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; color: #222; }
+h2 { margin-top: 1.5em; }
+.notice { color: #a00; }
+.heads, .grid { display: flex; }
+.heads .stage { font-weight: bold; text-align: center; padding: 0.25em; }
+.axis { flex: 0 0 4em; position: relative; }
+.stage { flex: 1 1 0; position: relative; min-width: 8em; }
+.grid .stage { border-left: 1px solid #ccc; }
+.grid { position: relative; }
+.line { position: absolute; left: 4em; right: 0; border-top: 1px dotted #ccc; }
+.hour { position: absolute; font-size: 0.8em; transform: translateY(-50%); }
+.event { position: absolute; left: 2px; right: 2px; box-sizing: border-box; overflow: hidden; padding: 2px 4px; border: 1px solid #333; border-radius: 3px; background: #eee; font-size: 0.8em; }
+.event.open { border-style: dashed; }
+.event .time { display: block; color: #555; }
+.main .event { background: #fdd; }
+.second .event { background: #ddf; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Days}}
+<section>
+<h2>{{.Label}}</h2>
+{{- if .Preliminary}}
+<p class="notice">The running order of this day is preliminary.</p>
+{{- end}}
+{{- range .Announcements}}
+<p class="announcement">{{.}}</p>
+{{- end}}
+{{- if .Stages}}
+<div class="heads">
+<div class="axis"></div>
+{{- range .Stages}}
+<div class="stage">{{.Label}}</div>
+{{- end}}
+</div>
+<div class="grid" style="height: {{.Height}}px">
+{{- range .Hours}}
+<div class="line" style="top: {{.Top}}px"></div>
+{{- end}}
+<div class="axis">
+{{- range .Hours}}
+<div class="hour" style="top: {{.Top}}px">{{.Label}}</div>
+{{- end}}
+</div>
+{{- range .Stages}}
+<div class="stage{{with .Kind}} {{.}}{{end}}">
+{{- range .Blocks}}
+<div class="event{{if .Open}} open{{end}}" style="top: {{.Top}}px; height: {{.Height}}px"><span class="time">{{.Time}}</span>{{if .URL}}<a href="{{.URL}}">{{.Label}}</a>{{else}}{{.Label}}{{end}}</div>
+{{- end}}
+</div>
+{{- end}}
+</div>
+{{- end}}
+{{- if .Unscheduled}}
+<p>Not yet scheduled:</p>
+<ul class="unscheduled">
+{{- range .Unscheduled}}
+<li>{{if .URL}}<a href="{{.URL}}">{{.Label}}</a>{{else}}{{.Label}}{{end}} ({{.Stage}}{{with .Time}}, {{.}}{{end}})</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,99 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTimelineEncoder(t *testing.T) {
+	var b bytes.Buffer
+	err := NewTimelineEncoder(&b).Encode(parseSample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ioutil.ReadFile("./testdata/sample_timeline.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("unexpected HTML; expected: %q; is: %q", expected, b.Bytes())
+	}
+}
+
+func TestTimelineEncoderDay(t *testing.T) {
+	d := &Day{
+		"Saturday 22.07.",
+		[]*Stage{
+			{"Main Stage", []*Event{
+				{"20:00 - 21:00", &TimeStamps{Start: 1500746400, End: 1500750000}, "Foo", "FOO", "", ""},
+				{"21:30", &TimeStamps{Start: 1500751800}, "Bar", "BAR", "http://example.com/bar", "42"},
+				{"22:15", &TimeStamps{Start: 1500754500}, "Baz", "BAZ", "", "43"},
+				{"TBA", nil, "Qux", "QUX", "", "44"},
+			}, StageKindMain, 1},
+			{"Empty Stage", []*Event{}, "", 2},
+		},
+		&TimeStamps{Start: 1500674400, End: 1500760800},
+		&TimeStamps{Start: 1500710400, End: 1500796800},
+		false,
+		nil,
+	}
+
+	enc := NewTimelineEncoder(nil)
+	enc.SetLocation(nil)
+	enc.SetMinuteHeight(1)
+
+	expected := &timelineDay{
+		Label:  "Saturday 22.07.",
+		Height: 240,
+		Hours: []timelineHour{
+			{0, "18:00"}, {60, "19:00"}, {120, "20:00"}, {180, "21:00"}, {240, "22:00"},
+		},
+		Stages: []*timelineStage{{"Main Stage", StageKindMain, []timelineBlock{
+			{0, 60, "20:00 - 21:00", "Foo", "", false},
+			{90, 45, "21:30", "Bar", "http://example.com/bar", true},
+			{135, 60, "22:15", "Baz", "", true},
+		}}},
+		Unscheduled: []timelineUnscheduled{{"Main Stage", "TBA", "Qux", ""}},
+	}
+
+	is := enc.day(d)
+	if !reflect.DeepEqual(is, expected) {
+		t.Errorf("unexpected day; expected: %+v; is: %+v", expected, is)
+	}
+}
+
+func TestTimelineEncoderEscaping(t *testing.T) {
+	ro := &RunningOrder{Days: []*Day{{
+		Label: "<Saturday>",
+		Stages: []*Stage{{Label: "Main", Events: []*Event{
+			{Time: "20:00", TimeStamps: &TimeStamps{Start: 1500746400}, Label: "<script>alert(1)</script>", URL: "javascript:alert(1)"},
+		}}},
+	}}}
+
+	var b bytes.Buffer
+	err := NewTimelineEncoder(&b).Encode(ro)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := b.String()
+	for _, unexpected := range []string{"<script>", "<Saturday>", "javascript:"} {
+		if strings.Contains(s, unexpected) {
+			t.Errorf("unescaped %q in HTML: %s", unexpected, s)
+		}
+	}
+	if !strings.Contains(s, "<title>Running Order</title>") {
+		t.Errorf("unexpected title in HTML: %s", s)
+	}
+}
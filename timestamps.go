@@ -7,42 +7,72 @@
 package mdjson
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-// timezone is the time.Location where the festival happens.
-var timezone *time.Location
-
-// init initializes the global context of the timestamp related functions.
-func init() {
-	var err error
-	timezone, err = time.LoadLocation("Europe/Ljubljana")
-	if err != nil {
-		panic(err)
-	}
-}
-
 // A TimeStamps contains two unix timestamps, that denote the start and end of
-// a time span.
+// a time span. End is 0 if the end is not known, e.g. for open-ended sets.
+//
+// StartTime, EndTime and Duration are only filled with SchemaV2 or later. They
+// contain the start and end as RFC 3339 strings in the time zone of the
+// festival and the length of the time span as ISO 8601 duration. EndTime and
+// Duration are empty if the end is not known.
 type TimeStamps struct {
 	Start int64 `json:"start"`
-	End   int64 `json:"end"`
+	End   int64 `json:"end,omitempty"`
+
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+	Duration  string `json:"duration,omitempty"`
 }
 
-// addTimeStampsToDay generates TimeStamps for the Day d and adds them to d.
-// d.Label has to be filled correctly, before calling this function. year will
-// be used as the year component of timestamps.
-func addTimeStampsToDay(year int, d *Day) error {
-	parsed, e := time.ParseInLocation("Monday 02.01.", d.Label, timezone)
+// addTimeStampsToDay generates TimeStamps and the Span for the Day d and adds
+// them to d. d.Label has to be filled correctly, before calling this function.
+// year is the year of the day, the location, date format and rollover cutoff
+// are taken from o.
+//
+// The day lasts from midnight to midnight and the span from the cutoff to the
+// cutoff on the next day, both in wall clock time (see localTime). So a day is
+// 23 or 25 hours long if the clocks are changed.
+func addTimeStampsToDay(d *Day, year int, o *options) error {
+	parsed, e := time.ParseInLocation(o.dateFormat, d.Label, o.location)
 	if e != nil {
 		return e
 	}
 
-	start := time.Date(year, parsed.Month(), parsed.Day(), 0, 0, 0, 0, timezone)
-	end := start.AddDate(0, 0, 1)
+	start := localTime(year, parsed.Month(), parsed.Day(), 0, o.location)
+	end := localTime(year, parsed.Month(), parsed.Day()+1, 0, o.location)
+
+	d.TimeStamps = &TimeStamps{Start: start.Unix(), End: end.Unix()}
 
-	d.TimeStamps = &TimeStamps{start.Unix(), end.Unix()}
+	spanStart := localTime(year, parsed.Month(), parsed.Day(), o.rolloverCutoff, o.location)
+	spanEnd := localTime(year, parsed.Month(), parsed.Day()+1, o.rolloverCutoff, o.location)
+
+	d.Span = &TimeStamps{Start: spanStart.Unix(), End: spanEnd.Unix()}
+
+	return nil
+}
+
+// checkEventInDay returns an error if the TimeStamps of the Event e do not fall
+// inside the Span of the Day d. Events and days without timestamps are not
+// checked.
+func checkEventInDay(e *Event, d *Day) error {
+	if e.TimeStamps == nil || d.Span == nil {
+		return nil
+	}
+
+	if e.TimeStamps.Start < d.Span.Start || e.TimeStamps.Start >= d.Span.End {
+		return fmt.Errorf("event at %q starts outside of its day %q", e.Time, d.Label)
+	}
+
+	if e.TimeStamps.End > d.Span.End {
+		return fmt.Errorf("event at %q ends outside of its day %q", e.Time, d.Label)
+	}
 
 	return nil
 }
@@ -50,39 +80,208 @@ func addTimeStampsToDay(year int, d *Day) error {
 // addTimeStampsToEvent generates TimeStamps for Event e and adds them to e.
 // The time.Time d that denotes the start for the day of the event will be used
 // to generate the timestamps for the event.
-// e.Time has to be filled correctly, before calling this function.
-// If the time component of a time stamp is smaller than 10:00, it is assumed,
-// that the event belongs to the next day.
-func addTimeStampsToEvent(e *Event, d time.Time) error {
-	if strings.TrimSpace(e.Time) == "-" {
+// e.Time has to be filled correctly, before calling this function (see
+// parseTimeRange for the understood formats).
+// If the start of the event is before cutoff, it is assumed, that the event
+// belongs to the next day. The end is the first occurrence of its time of day
+// after the start, so "23:30 - 00:30" ends on the next day. An error is
+// returned if the start and the end are the same time of day, as such a range
+// has no sensible length.
+func addTimeStampsToEvent(e *Event, d time.Time, cutoff time.Duration) error {
+	start, end, err := parseTimeRange(e.Time)
+	if err != nil {
+		return err
+	}
+
+	if start == unknownTime {
 		return nil
 	}
 
-	pf := func(s string, d time.Time) (time.Time, error) {
-		p, err := time.ParseInLocation("15:04", s, timezone)
-		if err != nil {
-			return time.Now(), err
+	day := d.Day()
+	if start < cutoff {
+		day++
+	}
+
+	startTime := localTime(d.Year(), d.Month(), day, start, d.Location())
+	ts := &TimeStamps{Start: startTime.Unix()}
+
+	if end != unknownTime {
+		if end == start {
+			return fmt.Errorf("time range %q has no length", e.Time)
 		}
 
-		n := time.Date(d.Year(), d.Month(), d.Day(), p.Hour(), p.Minute(), 0, 0, timezone)
-		if n.Hour() < 10 {
-			n = n.AddDate(0, 0, 1)
+		endTime := localTime(d.Year(), d.Month(), day, end, d.Location())
+		if !endTime.After(startTime) {
+			endTime = localTime(d.Year(), d.Month(), day+1, end, d.Location())
+		}
+		if !endTime.After(startTime) {
+			return fmt.Errorf("time range %q ends before it starts", e.Time)
+		}
+
+		ts.End = endTime.Unix()
+	}
+
+	e.TimeStamps = ts
+	return nil
+}
+
+// localTime returns the time.Time at the time of day t on the given date in
+// loc. Values outside their usual ranges are normalized as by time.Date, e.g.
+// the 32nd of July is the 1st of August and a t of 24 hours is midnight at
+// the end of the day.
+//
+// Unlike time.Date, which does not guarantee the result for such times,
+// localTime resolves wall clock times that are skipped or repeated when the
+// clocks are changed using the following rules:
+//
+//   - A time that does not exist, because the clocks are set forward, is moved
+//     forward by the length of the gap, e.g. 02:30 becomes 03:30 if the
+//     clocks are set forward from 02:00 to 03:00. This includes midnight in
+//     zones that change the clocks at midnight.
+//   - A time that exists twice, because the clocks are set back, denotes its
+//     first occurrence, e.g. 02:30 is the 02:30 in summer time if the clocks
+//     are set back from 03:00 to 02:00.
+func localTime(year int, month time.Month, day int, t time.Duration, loc *time.Location) time.Time {
+	wall := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Add(t)
+
+	// The candidates are the wall clock time interpreted using the offsets
+	// in effect a day before and a day after. Clocks are not changed more
+	// than once a day.
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	early := wall.Add(-time.Duration(before) * time.Second).In(loc)
+	late := wall.Add(-time.Duration(after) * time.Second).In(loc)
+
+	earlyValid := sameWallClock(early, wall)
+	lateValid := sameWallClock(late, wall)
+
+	switch {
+	case earlyValid && lateValid:
+		// The time exists twice.
+		if late.Before(early) {
+			return late
 		}
+		return early
+	case lateValid:
+		return late
+	default:
+		// Either only early is valid or the time does not exist. In
+		// the latter case the offset before the gap moves it forward
+		// by the length of the gap.
+		return early
+	}
+}
+
+// sameWallClock returns true if t shows the same wall clock time as the UTC
+// time wall.
+func sameWallClock(t, wall time.Time) bool {
+	y, m, d := t.Date()
+	wy, wm, wd := wall.Date()
+
+	return y == wy && m == wm && d == wd &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
+}
+
+// unknownTime is returned by parseTimeRange for times that are not known.
+const unknownTime time.Duration = -1
+
+// unknownTimes contains the lower case placeholders used for times that have
+// not been published yet.
+var unknownTimes = map[string]bool{
+	"-":   true,
+	"–":   true,
+	"—":   true,
+	"?":   true,
+	"tba": true,
+	"tbc": true,
+	"tbd": true,
+}
+
+// openEnds contains the lower case placeholders used for the end of sets that
+// have no fixed end. An empty end, e.g. "22:30 -", is open as well.
+var openEnds = map[string]bool{
+	"open":     true,
+	"open end": true,
+	"late":     true,
+	"end":      true,
+}
+
+// rangeSeparators contains the runes that separate the start and the end of a
+// time range.
+const rangeSeparators = "-–—−"
+
+// parseTimeRange parses the time of an event and returns the start and end as
+// times of day. Unknown times are returned as unknownTime.
+//
+// The following formats are understood:
+//
+//	"20:30 - 21:15"  a range; the separator may be a hyphen or a dash, the
+//	                 spaces around it are optional
+//	"20:30"          a single start time
+//	"20:30 - "       an open-ended set, the end may also be "open", "late",
+//	                 "end" or any placeholder below
+//	"-", "TBA"       the time is not yet known, as are "TBC", "TBD" and "?"
+//	"TBA - TBA"      a range with a placeholder as start is not yet known
+//	                 either, whatever its end is
+//
+// Times are given as "15:04", "15.04" or "15h04"; "24:00" denotes midnight at
+// the end of the day.
+func parseTimeRange(s string) (start, end time.Duration, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return unknownTime, unknownTime, fmt.Errorf("missing time")
+	}
 
-		return n, nil
+	if unknownTimes[strings.ToLower(s)] {
+		return unknownTime, unknownTime, nil
 	}
 
-	timeStrings := strings.Split(e.Time, " - ")
-	start, err := pf(timeStrings[0], d)
+	startString, endString := s, ""
+	if i := strings.IndexAny(s, rangeSeparators); i >= 0 {
+		startString = strings.TrimSpace(s[:i])
+		_, size := utf8.DecodeRuneInString(s[i:])
+		endString = strings.TrimSpace(s[i+size:])
+	}
+
+	if unknownTimes[strings.ToLower(startString)] {
+		return unknownTime, unknownTime, nil
+	}
+
+	start, err = parseClock(startString)
 	if err != nil {
-		return err
+		return unknownTime, unknownTime, fmt.Errorf("unexpected time range %q: %v", s, err)
+	}
+
+	l := strings.ToLower(endString)
+	if l == "" || openEnds[l] || unknownTimes[l] {
+		return start, unknownTime, nil
 	}
 
-	end, err := pf(timeStrings[1], d)
+	end, err = parseClock(endString)
 	if err != nil {
-		return err
+		return unknownTime, unknownTime, fmt.Errorf("unexpected time range %q: %v", s, err)
 	}
 
-	e.TimeStamps = &TimeStamps{start.Unix(), end.Unix()}
-	return nil
+	return start, end, nil
+}
+
+// parseClock parses the time of day s, e.g. "20:30", and returns it as the
+// duration since midnight.
+func parseClock(s string) (time.Duration, error) {
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return unknownTime, fmt.Errorf("invalid time %q", s)
+	}
+
+	h, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	if h > 24 || minute > 59 || (h == 24 && minute != 0) {
+		return unknownTime, fmt.Errorf("invalid time %q", s)
+	}
+
+	return time.Duration(h)*time.Hour + time.Duration(minute)*time.Minute, nil
 }
+
+// clockPattern matches the time of day understood by parseClock.
+var clockPattern = regexp.MustCompile(`^([0-9]{1,2})\s*[:.hH]\s*([0-9]{2})$`)
@@ -29,20 +29,23 @@ func TestAddTimeStampsToDays(t *testing.T) {
 		year     int
 		day      *Day
 		expected *TimeStamps
+		span     *TimeStamps
 	}{
-		{2017, &Day{"Saturday 22.07.", nil, nil, nil}, &TimeStamps{1500674400, 1500760800}},
-		{2016, &Day{"Wednesday 15.06.", nil, nil, nil}, &TimeStamps{1465941600, 1466028000}},
+		{2017, &Day{"Saturday 22.07.", nil, nil, nil, false, nil}, &TimeStamps{Start: 1500674400, End: 1500760800}, &TimeStamps{Start: 1500710400, End: 1500796800}},
+		{2016, &Day{"Wednesday 15.06.", nil, nil, nil, false, nil}, &TimeStamps{Start: 1465941600, End: 1466028000}, &TimeStamps{Start: 1465977600, End: 1466064000}},
+		{2017, &Day{"Sunday 26.03.", nil, nil, nil, false, nil}, &TimeStamps{Start: 1490482800, End: 1490565600}, &TimeStamps{Start: 1490515200, End: 1490601600}},
 	}
 
 	for _, test := range ts {
 		t.Run(fmt.Sprintf("%s %d", test.day.Label, test.year), func(t *testing.T) {
-			err := addTimeStampsToDay(test.year, test.day)
+			err := addTimeStampsToDay(test.day, test.year, newOptions())
 			if err != nil {
 				t.Errorf("unexpected error: %s", err)
 			}
 
 			is := test.day.TimeStamps
 			t.Run("check timestamps", compareTimeStampsPointers(is, test.expected))
+			t.Run("check span", compareTimeStampsPointers(test.day.Span, test.span))
 		})
 	}
 }
@@ -53,16 +56,35 @@ func TestAddTimeStampsToEvent(t *testing.T) {
 		day      time.Time
 		expected *TimeStamps
 	}{
-		{&Event{" - ", nil, "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, timezone), nil},
-		{&Event{" - ", nil, "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, timezone), nil},
-		{&Event{"20:30 - 21:15", nil, "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, timezone), &TimeStamps{1500748200, 1500750900}},
-		{&Event{"23:15 - 00:30", nil, "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, timezone), &TimeStamps{1466025300, 1466029800}},
-		{&Event{"00:30 - 01:15", nil, "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, timezone), &TimeStamps{1466029800, 1466032500}},
+		{&Event{" - ", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{" - ", nil, "", "", "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"20:30 - 21:15", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200, End: 1500750900}},
+		{&Event{"23:15 - 00:30", nil, "", "", "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1466025300, End: 1466029800}},
+		{&Event{"00:30 - 01:15", nil, "", "", "", ""}, time.Date(2016, 6, 15, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1466029800, End: 1466032500}},
+		{&Event{"20:30–21:15", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200, End: 1500750900}},
+		{&Event{"20.30 - 21.15", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200, End: 1500750900}},
+		{&Event{"20h30-21h15", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200, End: 1500750900}},
+		{&Event{"23:00 - 24:00", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500757200, End: 1500760800}},
+		{&Event{"09:00 - 10:00", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500793200, End: 1500796800}},
+		{&Event{"23:30 - 10:15", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500759000, End: 1500797700}},
+		{&Event{"08:30 - 10:30", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500791400, End: 1500798600}},
+		{&Event{"20:30", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200}},
+		{&Event{"20:30 -", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200}},
+		{&Event{"20:30 - open end", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200}},
+		{&Event{"20:30 – TBA", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), &TimeStamps{Start: 1500748200}},
+		{&Event{"TBA", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{" tbc ", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"?", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"—", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"TBA - TBA", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"TBC - TBC", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"? - ?", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
+		{&Event{"tba – 22:00", nil, "", "", "", ""}, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), nil},
 	}
 
 	for i, test := range ts {
 		t.Run(fmt.Sprintf("test %d", i), func(t *testing.T) {
-			err := addTimeStampsToEvent(test.event, test.day)
+			err := addTimeStampsToEvent(test.event, test.day, DefaultRolloverCutoff)
 			if err != nil {
 				t.Errorf("unexpected error: %s", err)
 			}
@@ -72,3 +94,199 @@ func TestAddTimeStampsToEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestAddTimeStampsToEventInvalid(t *testing.T) {
+	ts := []string{"22:30 - 00:00 - 01:00", "", "24:30 - 01:00", "24:15", "22:60", "2x:00", "22:30 - soon", "- 22:30", "2230", "20:00 - 20:00", "09:00 - 09:00"}
+
+	for _, test := range ts {
+		t.Run(test, func(t *testing.T) {
+			e := &Event{test, nil, "", "", "", ""}
+			err := addTimeStampsToEvent(e, time.Date(2017, 7, 22, 0, 0, 0, 0, defaultLocation), DefaultRolloverCutoff)
+			if err == nil {
+				t.Errorf("expected error did not occur; timestamps: %v", e.TimeStamps)
+			}
+		})
+	}
+}
+
+func TestCheckEventInDay(t *testing.T) {
+	d := &Day{"Saturday 22.07.", nil, nil, &TimeStamps{Start: 1500710400, End: 1500796800}, false, nil}
+
+	ts := []struct {
+		timestamps *TimeStamps
+		valid      bool
+	}{
+		{nil, true},
+		{&TimeStamps{Start: 1500710400, End: 1500714000}, true},
+		{&TimeStamps{Start: 1500793200, End: 1500796800}, true},
+		{&TimeStamps{Start: 1500793200}, true},
+		{&TimeStamps{Start: 1500706800, End: 1500710400}, false},
+		{&TimeStamps{Start: 1500796800, End: 1500800400}, false},
+		{&TimeStamps{Start: 1500793200, End: 1500800400}, false},
+	}
+
+	for i, test := range ts {
+		t.Run(fmt.Sprintf("test %d", i), func(t *testing.T) {
+			e := &Event{"", test.timestamps, "", "", "", ""}
+			err := checkEventInDay(e, d)
+			if (err == nil) != test.valid {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddTimeStampsToEventInDay(t *testing.T) {
+	for _, cutoff := range []time.Duration{0, DefaultRolloverCutoff, 6 * time.Hour} {
+		d := &Day{"Saturday 22.07.", nil, nil, nil, false, nil}
+		err := addTimeStampsToDay(d, 2017, newOptions(RolloverCutoff(cutoff)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Unix(d.TimeStamps.Start, 0).In(defaultLocation)
+
+		for _, tm := range []string{"00:10 - 01:20", "09:30 - 09:59", "10:00 - 11:00", "22:30 - 00:00", "23:59"} {
+			t.Run(fmt.Sprintf("%v %s", cutoff, tm), func(t *testing.T) {
+				e := &Event{tm, nil, "", "", "", ""}
+				err := addTimeStampsToEvent(e, start, cutoff)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = checkEventInDay(e, d)
+				if err != nil {
+					t.Errorf("event not inside its day: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestAddTimeStampsToEventOutsideDay(t *testing.T) {
+	d := &Day{"Saturday 22.07.", nil, nil, nil, false, nil}
+	err := addTimeStampsToDay(d, 2017, newOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(d.TimeStamps.Start, 0).In(defaultLocation)
+
+	for _, tm := range []string{"23:30 - 10:15", "08:30 - 10:30", "09:59 - 10:01"} {
+		t.Run(tm, func(t *testing.T) {
+			e := &Event{tm, nil, "", "", "", ""}
+			err := addTimeStampsToEvent(e, start, DefaultRolloverCutoff)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkEventInDay(e, d)
+			if err == nil {
+				t.Errorf("expected error did not occur; timestamps: %v", e.TimeStamps)
+			}
+		})
+	}
+}
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loc
+}
+
+func TestLocalTime(t *testing.T) {
+	ts := []struct {
+		name     string
+		location string
+		date     time.Time
+		t        time.Duration
+		expected int64
+	}{
+		{"ljubljana", "Europe/Ljubljana", time.Date(2017, 7, 22, 0, 0, 0, 0, time.UTC), 20*time.Hour + 30*time.Minute, 1500748200},
+		{"ljubljana_gap", "Europe/Ljubljana", time.Date(2017, 3, 26, 0, 0, 0, 0, time.UTC), 2*time.Hour + 30*time.Minute, 1490491800},
+		{"ljubljana_ambiguous", "Europe/Ljubljana", time.Date(2017, 10, 29, 0, 0, 0, 0, time.UTC), 2*time.Hour + 30*time.Minute, 1509237000},
+		{"ljubljana_normalized_day", "Europe/Ljubljana", time.Date(2017, 7, 32, 0, 0, 0, 0, time.UTC), 0, 1501538400},
+		{"new_york_gap", "America/New_York", time.Date(2017, 3, 12, 0, 0, 0, 0, time.UTC), 2*time.Hour + 30*time.Minute, 1489303800},
+		{"new_york_ambiguous", "America/New_York", time.Date(2017, 11, 5, 0, 0, 0, 0, time.UTC), time.Hour + 30*time.Minute, 1509859800},
+		{"sydney_gap", "Australia/Sydney", time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC), 2*time.Hour + 30*time.Minute, 1506789000},
+		{"sydney_ambiguous", "Australia/Sydney", time.Date(2017, 4, 2, 0, 0, 0, 0, time.UTC), 2*time.Hour + 30*time.Minute, 1491060600},
+		{"santiago_midnight_gap", "America/Santiago", time.Date(2022, 9, 11, 0, 0, 0, 0, time.UTC), 0, 1662868800},
+		{"kolkata", "Asia/Kolkata", time.Date(2017, 7, 22, 0, 0, 0, 0, time.UTC), 0, 1500661800},
+		{"utc_midnight_end", "UTC", time.Date(2017, 7, 22, 0, 0, 0, 0, time.UTC), 24 * time.Hour, 1500768000},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			loc := loadLocation(t, test.location)
+
+			is := localTime(test.date.Year(), test.date.Month(), test.date.Day(), test.t, loc)
+			if is.Unix() != test.expected {
+				t.Errorf("unexpected time; is %v (%d); expected %v", is, is.Unix(), time.Unix(test.expected, 0).In(loc))
+			}
+			if is.Location() != loc {
+				t.Errorf("unexpected location; is %v; expected %v", is.Location(), loc)
+			}
+		})
+	}
+}
+
+func TestAddTimeStampsToDaysInZones(t *testing.T) {
+	ts := []struct {
+		location string
+		year     int
+		label    string
+		expected *TimeStamps
+		span     *TimeStamps
+	}{
+		{"America/New_York", 2017, "Sunday 12.03.", &TimeStamps{Start: 1489294800, End: 1489377600}, &TimeStamps{Start: 1489327200, End: 1489413600}},
+		{"America/Santiago", 2022, "Sunday 11.09.", &TimeStamps{Start: 1662868800, End: 1662951600}, &TimeStamps{Start: 1662901200, End: 1662987600}},
+		{"Australia/Sydney", 2017, "Sunday 02.04.", &TimeStamps{Start: 1491051600, End: 1491141600}, &TimeStamps{Start: 1491091200, End: 1491177600}},
+		{"Asia/Kolkata", 2017, "Saturday 22.07.", &TimeStamps{Start: 1500661800, End: 1500748200}, &TimeStamps{Start: 1500697800, End: 1500784200}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.location, func(t *testing.T) {
+			d := &Day{test.label, nil, nil, nil, false, nil}
+			err := addTimeStampsToDay(d, test.year, newOptions(Location(loadLocation(t, test.location))))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			t.Run("check timestamps", compareTimeStampsPointers(d.TimeStamps, test.expected))
+			t.Run("check span", compareTimeStampsPointers(d.Span, test.span))
+		})
+	}
+}
+
+func TestAddTimeStampsToEventInZones(t *testing.T) {
+	ts := []struct {
+		name     string
+		location string
+		day      time.Time
+		time     string
+		expected *TimeStamps
+	}{
+		{"ljubljana_fall_back", "Europe/Ljubljana", time.Date(2017, 10, 28, 0, 0, 0, 0, time.UTC), "01:30 - 03:30", &TimeStamps{Start: 1509233400, End: 1509244200}},
+		{"ljubljana_ambiguous", "Europe/Ljubljana", time.Date(2017, 10, 28, 0, 0, 0, 0, time.UTC), "02:00 - 02:45", &TimeStamps{Start: 1509235200, End: 1509237900}},
+		{"ljubljana_spring_forward", "Europe/Ljubljana", time.Date(2017, 3, 25, 0, 0, 0, 0, time.UTC), "01:30 - 02:30", &TimeStamps{Start: 1490488200, End: 1490491800}},
+		{"new_york_spring_forward", "America/New_York", time.Date(2017, 3, 11, 0, 0, 0, 0, time.UTC), "01:30 - 02:30", &TimeStamps{Start: 1489300200, End: 1489303800}},
+		{"new_york_fall_back", "America/New_York", time.Date(2017, 11, 4, 0, 0, 0, 0, time.UTC), "23:30 - 01:30", &TimeStamps{Start: 1509852600, End: 1509859800}},
+		{"new_york_new_year", "America/New_York", time.Date(2017, 12, 31, 0, 0, 0, 0, time.UTC), "23:00 - 00:00", &TimeStamps{Start: 1514779200, End: 1514782800}},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			loc := loadLocation(t, test.location)
+			d := localTime(test.day.Year(), test.day.Month(), test.day.Day(), 0, loc)
+
+			e := &Event{test.time, nil, "", "", "", ""}
+			err := addTimeStampsToEvent(e, d, DefaultRolloverCutoff)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			t.Run("check timestamps", compareTimeStampsPointers(e.TimeStamps, test.expected))
+		})
+	}
+}
@@ -7,31 +7,11 @@
 package mdjson
 
 import (
-	"fmt"
-	"runtime"
 	"strings"
 
 	"golang.org/x/net/html"
 )
 
-// protect calls f and recovers any panics (that are not caused by
-// runtime.Errors) and sends corresponding errors via err. When f has finished a
-// single true is send via done.
-func protect(err chan<- error, done chan<- bool, f func()) {
-	defer func() {
-		if p := recover(); p != nil {
-			if _, ok := p.(runtime.Error); ok {
-				panic(p)
-			}
-
-			err <- fmt.Errorf("%v", p)
-		}
-	}()
-
-	f()
-	done <- true
-}
-
 // hasAttributes returns true if the html.Attributes in a contain an attribute
 // with key k and a value of v. If the attribute contains multiple value it is
 // sufficient if is contained in the values.
@@ -66,52 +46,89 @@ func getAttributeValue(a []html.Attribute, k string) string {
 	return ""
 }
 
-// A node embeds an *html.Node and adds some convenience functions that are more
-// robust against formatting changes in the HTML source.
-type node struct {
-	*html.Node
-}
-
-// NewNode creates and initializes a new *node embedding n. If n is nil, the new
-// *node will also be nil.
-func newNode(n *html.Node) *node {
+// elementText returns the whitespace normalized text of the direct text
+// children of n. If there is no such text, the whitespace normalized text of
+// all descendants of n is returned. An empty string is returned if n is nil.
+func elementText(n *html.Node) string {
 	if n == nil {
-		return nil
-	}
-
-	return &node{n}
-}
-
-// firstNonEmptyChild returns the first non empty child of n. "Non empty" means
-// any node whose embedded *html.Node.Data consists not only of whitespace, as
-// defined by Unicode.
-func (n *node) firstNonEmptyChild() *node {
-	if n == nil {
-		return nil
+		return ""
 	}
 
+	var b strings.Builder
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if len(strings.TrimSpace(c.Data)) > 0 {
-			return &node{c}
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
 		}
 	}
 
-	return nil
+	s := strings.Join(strings.Fields(b.String()), " ")
+	if s == "" {
+		s = strings.Join(strings.Fields(nodeText(n)), " ")
+	}
+
+	return s
 }
 
-// nextNonEmptySibling returns the next non empty sibling of n. "Non empty"
-// means any node whose embedded *html.Node.Data consists not only of
-// whitespace, as defined by Unicode.
-func (n *node) nextNonEmptySibling() *node {
-	if n == nil {
-		return nil
+// noticeTexts splits the text contained in n into separate announcements. Line
+// breaks and block elements end an announcement. Leading asterisks, which are
+// commonly used as decoration, and surrounding whitespace are removed. Empty
+// announcements are dropped.
+func noticeTexts(n *html.Node) []string {
+	var texts []string
+	var b strings.Builder
+
+	flush := func() {
+		s := strings.Join(strings.Fields(b.String()), " ")
+		s = strings.TrimSpace(strings.TrimLeft(s, "*"))
+		if s != "" {
+			texts = append(texts, s)
+		}
+		b.Reset()
 	}
 
-	for s := n.NextSibling; s != nil; s = s.NextSibling {
-		if len(strings.TrimSpace(s.Data)) > 0 {
-			return &node{s}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+			return
+		case n.Type == html.ElementNode && n.Data == "br":
+			flush()
+			return
 		}
+
+		block := n.Type == html.ElementNode && isBlockElement(n.Data)
+		if block {
+			flush()
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if block {
+			flush()
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
 	}
+	flush()
+
+	return texts
+}
 
-	return nil
+// isBlockElement returns true if the element with the tag name tag is
+// rendered as a block by default.
+func isBlockElement(tag string) bool {
+	switch tag {
+	case "address", "article", "aside", "blockquote", "dd", "div", "dl", "dt",
+		"footer", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "li",
+		"main", "nav", "ol", "p", "pre", "section", "table", "tr", "ul":
+		return true
+	}
+
+	return false
 }
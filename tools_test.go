@@ -8,8 +8,9 @@ package mdjson
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
-	"time"
 
 	"golang.org/x/net/html"
 )
@@ -66,77 +67,68 @@ func TestGetAttributeValue(t *testing.T) {
 	}
 }
 
-func TestNewNodeNil(t *testing.T) {
-	n := newNode(nil)
-
-	if n != nil {
-		t.Errorf("newNode(nil) returns not nil: %v", n)
+func TestElementText(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(
+		"<div id='own'> Own <span>Child</span> text </div>" +
+			"<div id='children'> <span> Child </span> <b>text</b> </div>" +
+			"<div id='empty'> </div>"))
+	if err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestNewNodeNotNil(t *testing.T) {
-	hn := &html.Node{}
-	n := newNode(hn)
-
-	if n.Node != hn {
-		t.Errorf("unexpected inner node; expected %v; is %v", hn, n.Node)
+	ts := []struct {
+		id       string
+		expected string
+	}{
+		{"own", "Own text"},
+		{"children", "Child text"},
+		{"empty", ""},
+		{"missing", ""},
 	}
-}
 
-func TestNilNodeFirstNonEmptyChild(t *testing.T) {
-	n := newNode(nil)
+	for _, test := range ts {
+		t.Run(test.id, func(t *testing.T) {
+			sel, err := compileSelector("#" + test.id)
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	if is := n.firstNonEmptyChild(); is != nil {
-		t.Error("firstNonEmptyChild() on nil node is not returning nil")
+			is := elementText(sel.first(doc))
+			if is != test.expected {
+				t.Errorf("elementText returned unexpected value; expected: %q; is %q", test.expected, is)
+			}
+		})
 	}
 }
 
-func TestNilNodeNextNonEmptySibling(t *testing.T) {
-	n := newNode(nil)
-
-	if is := n.nextNonEmptySibling(); is != nil {
-		t.Error("nextNonEmptySibling() on nil node is not returning nil")
+func TestNoticeTexts(t *testing.T) {
+	ts := []struct {
+		name     string
+		notice   string
+		expected []string
+	}{
+		{"br", "<span>*</span>First<br><span>Second</span>", []string{"First", "Second"}},
+		{"blocks", "<p>* First</p> <div> Second </div>", []string{"First", "Second"}},
+		{"inline", "First <b>and</b> only", []string{"First and only"}},
+		{"empty", " <span>*</span> <br> ", nil},
 	}
-}
-
-func TestProtectDone(t *testing.T) {
-	done := make(chan bool)
-	e := make(chan error)
-
-	funcCalled := false
 
-	go protect(e, done, func() {
-		funcCalled = true
-	})
-
-	select {
-	case <-done:
-		break
-	case err := <-e:
-		t.Fatalf("unexpected error: %v", err)
-	case <-time.After(time.Second):
-		t.Fatal("timeout")
-	}
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<div id='notice'>" + test.notice + "</div>"))
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	if !funcCalled {
-		t.Error("protect did not call wrapped function")
-	}
-}
+			sel, err := compileSelector("#notice")
+			if err != nil {
+				t.Fatal(err)
+			}
 
-func TestProtectError(t *testing.T) {
-	done := make(chan bool)
-	e := make(chan error)
-
-	go protect(e, done, func() {
-		panic("Testpanic")
-	})
-
-	select {
-	case <-done:
-		t.Fatal("unexpected success")
-	case <-e:
-		break
-	case <-time.After(time.Second):
-		t.Fatal("timeout")
+			is := noticeTexts(sel.first(doc))
+			if !reflect.DeepEqual(is, test.expected) {
+				t.Errorf("noticeTexts returned unexpected value; expected: %q; is %q", test.expected, is)
+			}
+		})
 	}
 }
@@ -0,0 +1,217 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Severity describes how serious an Issue is.
+type Severity string
+
+// The severities of Issues.
+const (
+	// SeverityWarning is used for issues that may be correct, but are
+	// suspicious, e.g. a band playing twice.
+	SeverityWarning Severity = "warning"
+
+	// SeverityError is used for issues that can not be correct, e.g.
+	// overlapping sets on the same stage.
+	SeverityError Severity = "error"
+)
+
+// A Check names the invariant of a running order that is violated by an Issue.
+type Check string
+
+// The checks performed by Validate.
+const (
+	CheckEmptyDay       Check = "empty_day"
+	CheckEmptyStage     Check = "empty_stage"
+	CheckMissingTime    Check = "missing_time"
+	CheckEndBeforeStart Check = "end_before_start"
+	CheckOutsideDay     Check = "outside_day"
+	CheckOverlap        Check = "overlap"
+	CheckDuplicateBand  Check = "duplicate_band"
+)
+
+// An Issue is a problem found in a RunningOrder by Validate.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Check    Check    `json:"check"`
+
+	// Day, Stage and Event locate the issue. Stage and Event are nil if
+	// the issue concerns a whole day or stage.
+	Day   *Day   `json:"-"`
+	Stage *Stage `json:"-"`
+	Event *Event `json:"-"`
+
+	// Message contains a human readable description of the issue.
+	Message string `json:"message"`
+}
+
+// String returns a human readable representation of i, including its
+// severity, check and location.
+func (i *Issue) String() string {
+	var loc []string
+	if i.Day != nil {
+		loc = append(loc, i.Day.Label)
+	}
+	if i.Stage != nil {
+		loc = append(loc, i.Stage.Label)
+	}
+	if i.Event != nil {
+		loc = append(loc, i.Event.Label)
+	}
+
+	return fmt.Sprintf("%s: %s: %s: %s", i.Severity, i.Check, strings.Join(loc, " / "), i.Message)
+}
+
+// Validate checks the invariants of ro and returns the issues found in document
+// order. The following checks are performed:
+//
+//	CheckEmptyDay        (warning) a day without stages
+//	CheckEmptyStage      (warning) a stage without events
+//	CheckMissingTime     (warning) an event without timestamps on a day
+//	                     with timestamps, e.g. "TBA"
+//	CheckEndBeforeStart  (error)   an event that does not end after it starts
+//	CheckOutsideDay      (error)   an event that is not inside the span of
+//	                     its day
+//	CheckOverlap         (error)   two events overlapping on the same stage
+//	CheckDuplicateBand   (warning) a band, identified by its ID or label,
+//	                     playing more than once
+//
+// An empty result means that ro passed all checks.
+func Validate(ro *RunningOrder) []*Issue {
+	v := &validator{bands: map[string]sighting{}}
+
+	for _, d := range ro.Days {
+		v.validateDay(d)
+	}
+
+	return v.issues
+}
+
+// HasErrors returns true if any of issues has SeverityError.
+func HasErrors(issues []*Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A validator collects the issues found by Validate.
+type validator struct {
+	issues []*Issue
+
+	// bands maps the keys of the bands seen so far to their first event.
+	bands map[string]sighting
+}
+
+// A sighting is an event together with its day.
+type sighting struct {
+	d *Day
+	e *Event
+}
+
+// add adds an issue to the issues found.
+func (v *validator) add(s Severity, c Check, d *Day, st *Stage, e *Event, format string, args ...interface{}) {
+	v.issues = append(v.issues, &Issue{s, c, d, st, e, fmt.Sprintf(format, args...)})
+}
+
+// validateDay checks the day d and its stages.
+func (v *validator) validateDay(d *Day) {
+	if len(d.Stages) == 0 {
+		v.add(SeverityWarning, CheckEmptyDay, d, nil, nil, "day has no stages")
+	}
+
+	for _, s := range d.Stages {
+		v.validateStage(d, s)
+	}
+}
+
+// validateStage checks the stage s on day d and its events.
+func (v *validator) validateStage(d *Day, s *Stage) {
+	if len(s.Events) == 0 {
+		v.add(SeverityWarning, CheckEmptyStage, d, s, nil, "stage has no events")
+	}
+
+	for _, e := range s.Events {
+		v.validateEvent(d, s, e)
+	}
+
+	v.validateOverlaps(d, s)
+}
+
+// validateEvent checks the event e on stage s on day d.
+func (v *validator) validateEvent(d *Day, s *Stage, e *Event) {
+	v.validateBand(d, s, e)
+
+	if e.TimeStamps == nil {
+		if d.TimeStamps != nil {
+			v.add(SeverityWarning, CheckMissingTime, d, s, e, "event has no time (%q)", e.Time)
+		}
+		return
+	}
+
+	if e.TimeStamps.End != 0 && e.TimeStamps.End <= e.TimeStamps.Start {
+		v.add(SeverityError, CheckEndBeforeStart, d, s, e, "event at %q does not end after its start", e.Time)
+	}
+
+	if err := checkEventInDay(e, d); err != nil {
+		v.add(SeverityError, CheckOutsideDay, d, s, e, "%v", err)
+	}
+}
+
+// validateBand checks whether the band of the event e on stage s on day d has
+// been seen before.
+func (v *validator) validateBand(d *Day, s *Stage, e *Event) {
+	key := e.ID
+	if key == "" {
+		key = dictionaryKey(e.Label)
+	}
+	if key == "" {
+		return
+	}
+
+	if first, ok := v.bands[key]; ok {
+		v.add(SeverityWarning, CheckDuplicateBand, d, s, e, "band also plays at %q on %s", first.e.Time, first.d.Label)
+		return
+	}
+
+	v.bands[key] = sighting{d, e}
+}
+
+// validateOverlaps checks whether events on stage s on day d overlap.
+func (v *validator) validateOverlaps(d *Day, s *Stage) {
+	var es []*Event
+	for _, e := range s.Events {
+		if e.TimeStamps != nil {
+			es = append(es, e)
+		}
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].TimeStamps.Start < es[j].TimeStamps.Start
+	})
+
+	// last is the event ending last of the events checked so far.
+	var last *Event
+	for _, e := range es {
+		if last != nil && e.TimeStamps.Start < last.TimeStamps.End {
+			v.add(SeverityError, CheckOverlap, d, s, e, "event at %q overlaps %q at %q", e.Time, last.Label, last.Time)
+		}
+
+		if last == nil || e.TimeStamps.End > last.TimeStamps.End {
+			last = e
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateSample(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ro, err := ParseRunningOrder(2017, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := Validate(ro)
+
+	expected := []Check{CheckMissingTime, CheckMissingTime}
+	if len(issues) != len(expected) {
+		t.Fatalf("unexpected number of issues; is %d; expected %d: %v", len(issues), len(expected), issues)
+	}
+	for i, is := range issues {
+		if is.Check != expected[i] || is.Severity != SeverityWarning {
+			t.Errorf("unexpected issue %d: %v", i, is)
+		}
+	}
+
+	if HasErrors(issues) {
+		t.Error("HasErrors returns true for warnings only")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Saturday 22.07.2017 in Europe/Ljubljana with the default cutoff.
+	day := func(stages ...*Stage) *Day {
+		return &Day{
+			"Saturday 22.07.",
+			stages,
+			&TimeStamps{Start: 1500674400, End: 1500760800},
+			&TimeStamps{Start: 1500710400, End: 1500796800},
+			false,
+			nil,
+		}
+	}
+	stage := func(events ...*Event) *Stage {
+		return &Stage{"Main Stage", events, StageKindMain, 1}
+	}
+	event := func(label, id string, ts *TimeStamps) *Event {
+		return &Event{"", ts, label, label, "", id}
+	}
+
+	ts := []struct {
+		name     string
+		ro       *RunningOrder
+		expected []Check
+	}{
+		{
+			"valid",
+			&RunningOrder{Days: []*Day{day(stage(
+				event("Foo", "1", &TimeStamps{Start: 1500748200, End: 1500750900}),
+				event("Bar", "2", &TimeStamps{Start: 1500750900, End: 1500753600}),
+				event("Baz", "3", &TimeStamps{Start: 1500753600}),
+			))}},
+			nil,
+		},
+		{
+			"empty",
+			&RunningOrder{Days: []*Day{day(), day(stage())}},
+			[]Check{CheckEmptyDay, CheckEmptyStage},
+		},
+		{
+			"missing_time",
+			&RunningOrder{Days: []*Day{
+				day(stage(event("Foo", "1", nil))),
+				{"Someday 23.07.", []*Stage{stage(event("Bar", "2", nil))}, nil, nil, false, nil},
+			}},
+			[]Check{CheckMissingTime},
+		},
+		{
+			"end_before_start",
+			&RunningOrder{Days: []*Day{day(stage(
+				event("Foo", "1", &TimeStamps{Start: 1500750900, End: 1500748200}),
+			))}},
+			[]Check{CheckEndBeforeStart},
+		},
+		{
+			"outside_day",
+			&RunningOrder{Days: []*Day{day(stage(
+				event("Foo", "1", &TimeStamps{Start: 1500706800, End: 1500710400}),
+				event("Bar", "2", &TimeStamps{Start: 1500793200, End: 1500800400}),
+			))}},
+			[]Check{CheckOutsideDay, CheckOutsideDay},
+		},
+		{
+			"overlap",
+			&RunningOrder{Days: []*Day{day(stage(
+				event("Foo", "1", &TimeStamps{Start: 1500748200, End: 1500757200}),
+				event("Bar", "2", &TimeStamps{Start: 1500750900, End: 1500753600}),
+				event("Baz", "3", &TimeStamps{Start: 1500753600, End: 1500756000}),
+			))}},
+			[]Check{CheckOverlap, CheckOverlap},
+		},
+		{
+			"no_overlap_across_stages",
+			&RunningOrder{Days: []*Day{day(
+				stage(event("Foo", "1", &TimeStamps{Start: 1500748200, End: 1500757200})),
+				stage(event("Bar", "2", &TimeStamps{Start: 1500750900, End: 1500753600})),
+			)}},
+			nil,
+		},
+		{
+			"duplicate_band",
+			&RunningOrder{Days: []*Day{
+				day(stage(event("Foo", "1", &TimeStamps{Start: 1500748200, End: 1500750900}))),
+				day(stage(
+					event("Foo (Acoustic)", "1", &TimeStamps{Start: 1500748200, End: 1500750900}),
+					event("Bar", "", &TimeStamps{Start: 1500750900, End: 1500753600}),
+					event("BAR", "", &TimeStamps{Start: 1500753600, End: 1500756000}),
+				)),
+			}},
+			[]Check{CheckDuplicateBand, CheckDuplicateBand},
+		},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			issues := Validate(test.ro)
+
+			if len(issues) != len(test.expected) {
+				t.Fatalf("unexpected number of issues; is %d; expected %d: %v", len(issues), len(test.expected), issues)
+			}
+			for i, is := range issues {
+				if is.Check != test.expected[i] {
+					t.Errorf("unexpected issue %d; is %v; expected check %q", i, is, test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIssueString(t *testing.T) {
+	d := &Day{Label: "Saturday 22.07."}
+	s := &Stage{Label: "Main Stage"}
+	e := &Event{Label: "Foo"}
+
+	is := (&Issue{SeverityError, CheckOverlap, d, s, e, "some message"}).String()
+	expected := "error: overlap: Saturday 22.07. / Main Stage / Foo: some message"
+	if is != expected {
+		t.Errorf("unexpected string; is %q; expected %q", is, expected)
+	}
+
+	if !HasErrors([]*Issue{{Severity: SeverityWarning}, {Severity: SeverityError}}) {
+		t.Error("HasErrors returns false for errors")
+	}
+
+	is = (&Issue{Severity: SeverityWarning, Check: CheckEmptyDay, Day: d, Message: "day has no stages"}).String()
+	if !strings.HasPrefix(is, "warning: empty_day: Saturday 22.07.: ") {
+		t.Errorf("unexpected string: %q", is)
+	}
+}
@@ -0,0 +1,373 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An Order describes how events are ordered.
+type Order string
+
+// The supported orders.
+const (
+	// OrderDocument keeps the events in the order of the running order.
+	OrderDocument Order = "document"
+
+	// OrderStart orders the events by their start. Events starting at the
+	// same time are ordered by their stage.
+	OrderStart Order = "start"
+
+	// OrderStage orders the events by their stage and then by their start.
+	// The main stages come first, then the second stages and then the
+	// stages of other or unknown kinds; stages of the same kind are kept in
+	// the order they first appear in the running order.
+	OrderStage Order = "stage"
+
+	// OrderAlphabetical orders the events case insensitively by their label
+	// and then by their start.
+	OrderAlphabetical Order = "alphabetical"
+)
+
+// ParseOrder returns the Order named s. An empty s selects OrderDocument.
+func ParseOrder(s string) (Order, error) {
+	switch o := Order(s); o {
+	case "":
+		return OrderDocument, nil
+	case OrderDocument, OrderStart, OrderStage, OrderAlphabetical:
+		return o, nil
+	}
+
+	return "", fmt.Errorf("mdjson: unknown order %q", s)
+}
+
+// Sort orders the events of every stage of ro according to by. OrderStart and
+// OrderStage both order the events of a stage by their start. Events without
+// timestamps are moved behind the events with timestamps. OrderStage
+// additionally orders the stages of every day by their kind and position (see
+// OrderStage). OrderDocument leaves ro untouched. Days are never reordered.
+func (ro *RunningOrder) Sort(by Order) {
+	for _, d := range ro.Days {
+		if by == OrderStage {
+			sort.SliceStable(d.Stages, func(i, j int) bool {
+				return stageBefore(d.Stages[i], d.Stages[j])
+			})
+		}
+
+		for _, s := range d.Stages {
+			switch by {
+			case OrderStart, OrderStage:
+				sort.SliceStable(s.Events, func(i, j int) bool {
+					return startsBefore(s.Events[i], s.Events[j])
+				})
+			case OrderAlphabetical:
+				sort.SliceStable(s.Events, func(i, j int) bool {
+					return labelBefore(s.Events[i], s.Events[j])
+				})
+			}
+		}
+	}
+}
+
+// Filter returns a copy of ro containing only the events on the given stages
+// and of the given bands. A stage is given by its label, which is compared
+// case insensitively, or its kind (see Stage.Kind). A band is given by its ID
+// or label (see Clashes). An empty list of stages or bands does not restrict
+// the events. Stages and days without events are omitted from the copy. The
+// events themselves are shared with ro.
+func Filter(ro *RunningOrder, stages, bands []string) *RunningOrder {
+	c := *ro
+	c.Days = []*Day{}
+
+	for _, d := range ro.Days {
+		dc := *d
+		dc.Stages = []*Stage{}
+
+		for _, s := range d.Stages {
+			if !isStage(s, stages) {
+				continue
+			}
+
+			sc := *s
+			sc.Events = []*Event{}
+			for _, e := range s.Events {
+				if isAnyBand(e, bands) {
+					sc.Events = append(sc.Events, e)
+				}
+			}
+
+			if len(sc.Events) > 0 {
+				dc.Stages = append(dc.Stages, &sc)
+			}
+		}
+
+		if len(dc.Stages) > 0 {
+			c.Days = append(c.Days, &dc)
+		}
+	}
+
+	return &c
+}
+
+// isStage returns true if stages is empty or contains the label or kind of s.
+func isStage(s *Stage, stages []string) bool {
+	if len(stages) == 0 {
+		return true
+	}
+
+	for _, st := range stages {
+		k := dictionaryKey(st)
+		if k == dictionaryKey(s.Label) || (s.Kind != "" && k == s.Kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAnyBand returns true if bands is empty or e belongs to any of bands.
+func isAnyBand(e *Event, bands []string) bool {
+	if len(bands) == 0 {
+		return true
+	}
+
+	for _, b := range bands {
+		if isBand(e, b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A FlatEvent is an Event together with its day and stage. The fields of the
+// Event are inlined in its JSON representation.
+type FlatEvent struct {
+	// Day is the label of the day of the event.
+	Day string `json:"day"`
+
+	// Stage is the label of the stage of the event.
+	Stage string `json:"stage"`
+
+	// StageKind is the kind of the stage of the event (see Stage.Kind).
+	StageKind string `json:"stage_kind,omitempty"`
+
+	*Event
+
+	// span is the span of the day of the event. It may be nil.
+	span *TimeStamps
+
+	// stage is the position of the stage in the order the stages first
+	// appear in the running order.
+	stage int
+}
+
+// Events returns all events of ro as a flat list ordered according to by.
+func Events(ro *RunningOrder, by Order) []*FlatEvent {
+	var es []*FlatEvent
+	stages := map[string]int{}
+
+	for _, d := range ro.Days {
+		for _, s := range d.Stages {
+			p, ok := stages[s.Label]
+			if !ok {
+				p = len(stages)
+				stages[s.Label] = p
+			}
+
+			for _, e := range s.Events {
+				es = append(es, &FlatEvent{d.Label, s.Label, s.Kind, e, d.Span, p})
+			}
+		}
+	}
+
+	sortEvents(es, by)
+
+	return es
+}
+
+// sortEvents orders es according to by.
+func sortEvents(es []*FlatEvent, by Order) {
+	var less func(a, b *FlatEvent) bool
+
+	switch by {
+	case OrderStart:
+		less = func(a, b *FlatEvent) bool {
+			if startsBefore(a.Event, b.Event) || startsBefore(b.Event, a.Event) {
+				return startsBefore(a.Event, b.Event)
+			}
+			return a.stage < b.stage
+		}
+	case OrderStage:
+		less = func(a, b *FlatEvent) bool {
+			ka, kb := stageKindRank(a.StageKind), stageKindRank(b.StageKind)
+			if ka != kb {
+				return ka < kb
+			}
+			if a.stage != b.stage {
+				return a.stage < b.stage
+			}
+			return startsBefore(a.Event, b.Event)
+		}
+	case OrderAlphabetical:
+		less = func(a, b *FlatEvent) bool {
+			return labelBefore(a.Event, b.Event)
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		return less(es[i], es[j])
+	})
+}
+
+// stageBefore returns true if the stage a comes before the stage b in
+// OrderStage.
+func stageBefore(a, b *Stage) bool {
+	ka, kb := stageKindRank(a.Kind), stageKindRank(b.Kind)
+	if ka != kb {
+		return ka < kb
+	}
+
+	return a.Position < b.Position
+}
+
+// stageKindRank returns the rank of the stage kind k in OrderStage.
+func stageKindRank(k string) int {
+	switch k {
+	case StageKindMain:
+		return 0
+	case StageKindSecond:
+		return 1
+	}
+
+	return 2
+}
+
+// startsBefore returns true if a starts before b. Events without timestamps
+// start after all events with timestamps.
+func startsBefore(a, b *Event) bool {
+	if a.TimeStamps == nil || b.TimeStamps == nil {
+		return a.TimeStamps != nil && b.TimeStamps == nil
+	}
+
+	return a.TimeStamps.Start < b.TimeStamps.Start
+}
+
+// labelBefore returns true if the label of a is case insensitively before the
+// label of b or, if both labels are equal, a starts before b.
+func labelBefore(a, b *Event) bool {
+	la, lb := strings.ToLower(a.Label), strings.ToLower(b.Label)
+	if la != lb {
+		return la < lb
+	}
+
+	return startsBefore(a, b)
+}
+
+// A BandGroup contains all events of a band.
+type BandGroup struct {
+	// Label is the label of the first event of the band.
+	Label string `json:"label"`
+
+	// ID is the ID of the band. It is empty if it can not be determined.
+	ID string `json:"id,omitempty"`
+
+	Events []*FlatEvent `json:"events"`
+}
+
+// GroupByBand returns the events of ro grouped by band. A band is identified
+// by its ID or, if there is none, case insensitively by its label. The events
+// of a band and the bands, by their first event, are ordered according to by.
+func GroupByBand(ro *RunningOrder, by Order) []*BandGroup {
+	var gs []*BandGroup
+	byKey := map[string]*BandGroup{}
+
+	for _, e := range Events(ro, by) {
+		key := bandKey(e.Event)
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &BandGroup{Label: e.Label, ID: e.ID}
+			byKey[key] = g
+			gs = append(gs, g)
+		}
+
+		g.Events = append(g.Events, e)
+	}
+
+	return gs
+}
+
+// bandKey returns the key identifying the band of e.
+func bandKey(e *Event) string {
+	if e.ID != "" {
+		return "id:" + e.ID
+	}
+
+	return "label:" + dictionaryKey(e.Label)
+}
+
+// A TimeSlot contains the events starting in a period of time.
+type TimeSlot struct {
+	// TimeStamps contains the start and the end of the slot. The end is
+	// not part of the slot.
+	*TimeStamps
+
+	Events []*FlatEvent `json:"events"`
+}
+
+// GroupByTimeSlot returns the events of ro grouped by the slot of length d
+// they start in. Slots are aligned to the start of the span of the day of
+// their events, so hourly slots start at full hours in the time zone of the
+// festival. The slots are ordered by their start, the events of a slot
+// according to by. Events without timestamps are omitted, as are slots
+// without events. d has to be positive.
+func GroupByTimeSlot(ro *RunningOrder, d time.Duration, by Order) []*TimeSlot {
+	var ss []*TimeSlot
+	byStart := map[int64]*TimeSlot{}
+
+	l := int64(d / time.Second)
+	if l <= 0 {
+		return nil
+	}
+
+	for _, e := range Events(ro, by) {
+		if e.TimeStamps == nil {
+			continue
+		}
+
+		var origin int64
+		if e.span != nil {
+			origin = e.span.Start
+		}
+
+		start := e.TimeStamps.Start - (e.TimeStamps.Start-origin)%l
+		if start > e.TimeStamps.Start {
+			start -= l
+		}
+
+		s, ok := byStart[start]
+		if !ok {
+			s = &TimeSlot{&TimeStamps{Start: start, End: start + l}, nil}
+			byStart[start] = s
+			ss = append(ss, s)
+		}
+
+		s.Events = append(s.Events, e)
+	}
+
+	sort.SliceStable(ss, func(i, j int) bool {
+		return ss[i].Start < ss[j].Start
+	})
+
+	return ss
+}
@@ -0,0 +1,309 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseSample(t *testing.T) *RunningOrder {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ro, err := ParseRunningOrder(2017, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ro
+}
+
+func labels(es []*FlatEvent) string {
+	var ls []string
+	for _, e := range es {
+		ls = append(ls, e.Label)
+	}
+
+	return strings.Join(ls, ", ")
+}
+
+func TestParseOrder(t *testing.T) {
+	ts := []struct {
+		s        string
+		expected Order
+		valid    bool
+	}{
+		{"", OrderDocument, true},
+		{"document", OrderDocument, true},
+		{"start", OrderStart, true},
+		{"stage", OrderStage, true},
+		{"alphabetical", OrderAlphabetical, true},
+		{"Start", "", false},
+		{"random", "", false},
+	}
+
+	for _, test := range ts {
+		t.Run(test.s, func(t *testing.T) {
+			o, err := ParseOrder(test.s)
+			if (err == nil) != test.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if o != test.expected {
+				t.Errorf("unexpected order; is %q; expected %q", o, test.expected)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	ts := []struct {
+		by       Order
+		expected string
+	}{
+		{OrderDocument, "Amon Amarth, Katatonia"},
+		{OrderStart, "Katatonia, Amon Amarth"},
+		{OrderStage, "Katatonia, Amon Amarth"},
+		{OrderAlphabetical, "Amon Amarth, Katatonia"},
+	}
+
+	for _, test := range ts {
+		t.Run(string(test.by), func(t *testing.T) {
+			ro := parseSample(t)
+			ro.Sort(test.by)
+
+			var es []*FlatEvent
+			for _, e := range ro.Days[1].Stages[0].Events {
+				es = append(es, &FlatEvent{Event: e})
+			}
+
+			is := labels(es)
+			if is != test.expected {
+				t.Errorf("unexpected order; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestSortStages(t *testing.T) {
+	newRunningOrder := func() *RunningOrder {
+		return &RunningOrder{Days: []*Day{{
+			Label: "Saturday 22.07.",
+			Stages: []*Stage{
+				{"Tent", []*Event{}, "", 1},
+				{"Second Stage", []*Event{}, StageKindSecond, 2},
+				{"Main Stage", []*Event{}, StageKindMain, 3},
+				{"Beach", []*Event{}, "", 4},
+			},
+		}}}
+	}
+
+	ts := []struct {
+		by       Order
+		expected string
+	}{
+		{OrderDocument, "Tent, Second Stage, Main Stage, Beach"},
+		{OrderStart, "Tent, Second Stage, Main Stage, Beach"},
+		{OrderStage, "Main Stage, Second Stage, Tent, Beach"},
+		{OrderAlphabetical, "Tent, Second Stage, Main Stage, Beach"},
+	}
+
+	for _, test := range ts {
+		t.Run(string(test.by), func(t *testing.T) {
+			ro := newRunningOrder()
+			ro.Sort(test.by)
+
+			var ls []string
+			for _, s := range ro.Days[0].Stages {
+				ls = append(ls, s.Label)
+			}
+
+			if is := strings.Join(ls, ", "); is != test.expected {
+				t.Errorf("unexpected order; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts := []struct {
+		by       Order
+		expected string
+	}{
+		{OrderDocument, "Tytus, Turbowarrior Of Steel, Amon Amarth, Katatonia, Kadavar, Doro"},
+		{OrderStart, "Katatonia, Amon Amarth, Kadavar, Doro, Tytus, Turbowarrior Of Steel"},
+		{OrderStage, "Katatonia, Amon Amarth, Doro, Tytus, Turbowarrior Of Steel, Kadavar"},
+		{OrderAlphabetical, "Amon Amarth, Doro, Kadavar, Katatonia, Turbowarrior Of Steel, Tytus"},
+	}
+
+	for _, test := range ts {
+		t.Run(string(test.by), func(t *testing.T) {
+			es := Events(parseSample(t), test.by)
+
+			is := labels(es)
+			if is != test.expected {
+				t.Errorf("unexpected order; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+
+	e := Events(parseSample(t), OrderDocument)[4]
+	if e.Day != "Tuesday 25.07." || e.Stage != "Boško Bursać Stage" || e.StageKind != StageKindSecond {
+		t.Errorf("unexpected location of event: %q, %q, %q", e.Day, e.Stage, e.StageKind)
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"day":"Tuesday 25.07.","stage":"Boško Bursać Stage","stage_kind":"second","time":"00:10 - 01:20",`
+	if !strings.HasPrefix(string(b), expected) {
+		t.Errorf("unexpected JSON representation; is %s; expected %s...", b, expected)
+	}
+}
+
+func TestGroupByBand(t *testing.T) {
+	event := func(label, id string, start int64) *Event {
+		return &Event{"", &TimeStamps{Start: start}, label, label, "", id}
+	}
+
+	ro := &RunningOrder{Days: []*Day{
+		{"Saturday 22.07.", []*Stage{
+			{"Main Stage", []*Event{event("Foo", "1", 300), event("Bar", "", 100)}, StageKindMain, 1},
+		}, nil, nil, false, nil},
+		{"Sunday 23.07.", []*Stage{
+			{"Main Stage", []*Event{event("Foo (Acoustic)", "1", 400), event("BAR", "", 200), event("Bar", "2", 500)}, StageKindMain, 1},
+		}, nil, nil, false, nil},
+	}}
+
+	ts := []struct {
+		by       Order
+		expected []string
+	}{
+		{OrderDocument, []string{"Foo, Foo (Acoustic)", "Bar, BAR", "Bar"}},
+		{OrderStart, []string{"Bar, BAR", "Foo, Foo (Acoustic)", "Bar"}},
+		{OrderAlphabetical, []string{"Bar, BAR", "Bar", "Foo, Foo (Acoustic)"}},
+	}
+
+	for _, test := range ts {
+		t.Run(string(test.by), func(t *testing.T) {
+			gs := GroupByBand(ro, test.by)
+
+			var is []string
+			for _, g := range gs {
+				is = append(is, labels(g.Events))
+			}
+
+			if strings.Join(is, "; ") != strings.Join(test.expected, "; ") {
+				t.Errorf("unexpected groups; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+
+	gs := GroupByBand(ro, OrderDocument)
+	if gs[0].Label != "Foo" || gs[0].ID != "1" || gs[1].ID != "" {
+		t.Errorf("unexpected groups: %q (%q), %q (%q)", gs[0].Label, gs[0].ID, gs[1].Label, gs[1].ID)
+	}
+}
+
+func TestGroupByTimeSlot(t *testing.T) {
+	ro := parseSample(t)
+
+	ts := []struct {
+		name     string
+		d        time.Duration
+		by       Order
+		expected []string
+	}{
+		{
+			"hourly",
+			time.Hour,
+			OrderDocument,
+			[]string{"20:00 Katatonia", "22:00 Amon Amarth", "00:00 Kadavar", "22:00 Doro"},
+		},
+		{
+			"daily",
+			24 * time.Hour,
+			OrderStart,
+			[]string{"10:00 Katatonia, Amon Amarth, Kadavar", "10:00 Doro"},
+		},
+		{
+			"invalid",
+			0,
+			OrderDocument,
+			nil,
+		},
+	}
+
+	loc, err := time.LoadLocation("Europe/Ljubljana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			ss := GroupByTimeSlot(ro, test.d, test.by)
+
+			var is []string
+			for _, s := range ss {
+				if s.End-s.Start != int64(test.d/time.Second) {
+					t.Errorf("unexpected length of slot: %d", s.End-s.Start)
+				}
+
+				start := time.Unix(s.Start, 0).In(loc).Format("15:04")
+				is = append(is, start+" "+labels(s.Events))
+			}
+
+			if strings.Join(is, "; ") != strings.Join(test.expected, "; ") {
+				t.Errorf("unexpected slots; is %q; expected %q", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	ts := []struct {
+		name     string
+		stages   []string
+		bands    []string
+		expected string
+	}{
+		{"none", nil, nil, "Tytus, Turbowarrior Of Steel, Amon Amarth, Katatonia, Kadavar, Doro"},
+		{"stage_label", []string{"boško  bursać stage"}, nil, "Kadavar"},
+		{"stage_kind", []string{StageKindMain}, nil, "Amon Amarth, Katatonia, Doro"},
+		{"bands", nil, []string{"doro", "539"}, "Kadavar, Doro"},
+		{"stages_and_bands", []string{StageKindMain, "Newforces Stage"}, []string{"Doro", "Kadavar", "Tytus"}, "Tytus, Doro"},
+		{"unknown", []string{"Unknown Stage"}, nil, ""},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			ro := parseSample(t)
+			f := Filter(ro, test.stages, test.bands)
+
+			is := labels(Events(f, OrderDocument))
+			if is != test.expected {
+				t.Errorf("unexpected events; is %q; expected %q", is, test.expected)
+			}
+
+			if labels(Events(ro, OrderDocument)) != "Tytus, Turbowarrior Of Steel, Amon Amarth, Katatonia, Kadavar, Doro" {
+				t.Error("Filter modified the running order")
+			}
+		})
+	}
+
+	f := Filter(parseSample(t), []string{"Boško Bursać Stage"}, nil)
+	if len(f.Days) != 1 || len(f.Days[0].Stages) != 1 || f.Year != 2017 {
+		t.Errorf("unexpected filtered running order: %d days", len(f.Days))
+	}
+}
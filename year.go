@@ -0,0 +1,158 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// yearSearchRange is the maximum distance in years from the reference year
+// that is searched when inferring the year of a running order. As the
+// calendar repeats at most every eleven years, every combination of date and
+// weekday is found.
+const yearSearchRange = 11
+
+// inferYear returns the year the running order in the document n takes place
+// in.
+//
+// The year is chosen from the years around the reference year, which is the
+// copyright year found in the document or, if there is none, the current
+// year. As running orders are not published years in advance, only years up to
+// the year after the reference year are considered. The year that matches the
+// weekdays of the most day labels wins; on a tie the year closest to the
+// reference year, and of those the earlier one. If the date format contains no
+// weekday, the reference year is returned.
+func inferYear(n *html.Node, o *options) int {
+	ref := copyrightYear(n, o)
+	if ref == 0 {
+		ref = o.referenceYear
+	}
+
+	labels := dayLabels(n, o)
+
+	best, bestMatches := ref, -1
+	for d := 0; d <= yearSearchRange; d++ {
+		ys := []int{ref - d}
+		if d <= 1 {
+			ys = append(ys, ref+d)
+		}
+
+		for _, y := range ys {
+			m := 0
+			yt := &yearTracker{year: y}
+			for _, l := range labels {
+				if labelMatchesYear(l, yt.yearOf(l, o), o) {
+					m++
+				}
+			}
+
+			if m > bestMatches {
+				best, bestMatches = y, m
+			}
+		}
+	}
+
+	return best
+}
+
+// A yearTracker assigns years to the days of a running order in document
+// order. A day whose date is at least six months before the date of the
+// previous day belongs to the next year, e.g. for a festival lasting from the
+// 30th of December to the 1st of January. Smaller steps back are assumed to
+// be days listed out of order.
+type yearTracker struct {
+	// year is the year of the previous day.
+	year int
+
+	// last is the month of the previous day. It is 0 before the first day.
+	last time.Month
+}
+
+// yearOf returns the year of the day labeled l. The label has to be given in
+// document order. If l can not be parsed, the year of the previous day is
+// returned.
+func (yt *yearTracker) yearOf(l string, o *options) int {
+	p, err := time.ParseInLocation(o.dateFormat, l, o.location)
+	if err != nil {
+		return yt.year
+	}
+
+	if yt.last != 0 && p.Month()+6 <= yt.last {
+		yt.year++
+	}
+	yt.last = p.Month()
+
+	return yt.year
+}
+
+// copyrightYear returns the year found by the copyright pattern of the layout
+// in the text of the document n. If there are multiple copyright notices, the
+// last one is used. 0 is returned if there is no copyright notice.
+func copyrightYear(n *html.Node, o *options) int {
+	if o.selectors.copyrightPattern == nil {
+		return 0
+	}
+
+	ms := o.selectors.copyrightPattern.FindAllStringSubmatch(nodeText(n), -1)
+	if len(ms) == 0 {
+		return 0
+	}
+
+	y, err := strconv.Atoi(ms[len(ms)-1][1])
+	if err != nil {
+		return 0
+	}
+
+	return y
+}
+
+// dayLabels returns the labels of all days found in the document n.
+func dayLabels(n *html.Node, o *options) []string {
+	if o.selectors.day.match(n) {
+		if l := dayLabel(n, o); l != "" {
+			return []string{l}
+		}
+		return nil
+	}
+
+	var labels []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		labels = append(labels, dayLabels(c, o)...)
+	}
+
+	return labels
+}
+
+// dayLabel returns the label of the day found at n. An empty string is
+// returned if the day has no label.
+func dayLabel(n *html.Node, o *options) string {
+	l := elementText(o.selectors.dayLabel.first(n))
+
+	// For some reason there is an additional space behind each date
+	// separator
+	return strings.Replace(l, ". ", ".", -1)
+}
+
+// labelMatchesYear returns true if the day label l denotes a valid date in
+// year, i.e. if formatting the date in year yields l again. This fails if the
+// weekday contained in l does not match the date in year.
+func labelMatchesYear(l string, year int, o *options) bool {
+	p, err := time.ParseInLocation(o.dateFormat, l, o.location)
+	if err != nil {
+		return false
+	}
+
+	d := time.Date(year, p.Month(), p.Day(), 0, 0, 0, 0, o.location)
+
+	return strings.EqualFold(
+		strings.Join(strings.Fields(d.Format(o.dateFormat)), " "),
+		strings.Join(strings.Fields(l), " "))
+}
@@ -0,0 +1,218 @@
+// "THE BEER-WARE LICENSE" (Revision 42):
+// <tobias.rehbein@web.de> wrote this file. As long as you retain this notice
+// you can do whatever you want with this stuff. If we meet some day, and you
+// think this stuff is worth it, you can buy me a beer in return.
+//                                                             Tobias Rehbein
+
+package mdjson
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestInferYear(t *testing.T) {
+	ts := []struct {
+		name      string
+		reference int
+		doc       string
+		expected  int
+	}{
+		{"sample_next_year", 2018, "", 2017},
+		{"sample_same_year", 2017, "", 2017},
+		{"sample_later", 2026, "", 2023},
+		{"copyright", 2026, "<footer>© 2017 MetalDays</footer>", 2017},
+		{"copyright_range", 2026, "<footer>Copyright 2012-2017</footer>", 2017},
+		{"stale_copyright", 2026, "<footer>(c) 2016</footer>", 2017},
+	}
+
+	for _, test := range ts {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.ReadFile("./testdata/sample.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := html.Parse(strings.NewReader(string(f) + test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o := newOptions()
+			o.referenceYear = test.reference
+
+			if is := inferYear(n, o); is != test.expected {
+				t.Errorf("unexpected year; is %d; expected %d", is, test.expected)
+			}
+		})
+	}
+}
+
+func TestInferYearWithoutWeekday(t *testing.T) {
+	n, err := html.Parse(strings.NewReader(
+		"<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>22. 07.<span>Main</span></div></div></div>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := newOptions(DateFormat("02.01."))
+	o.referenceYear = 2020
+
+	if is := inferYear(n, o); is != 2020 {
+		t.Errorf("unexpected year; is %d; expected %d", is, 2020)
+	}
+}
+
+func TestCopyrightYear(t *testing.T) {
+	ts := []struct {
+		doc      string
+		expected int
+	}{
+		{"<p>© 2017 MetalDays</p>", 2017},
+		{"<p>&copy; 2016 – 2018</p>", 2018},
+		{"<p>COPYRIGHT 2015</p><p>© 2016</p>", 2016},
+		{"<p>MetalDays 2017</p>", 0},
+	}
+
+	for _, test := range ts {
+		t.Run(test.doc, func(t *testing.T) {
+			n, err := html.Parse(strings.NewReader(test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if is := copyrightYear(n, newOptions()); is != test.expected {
+				t.Errorf("unexpected year; is %d; expected %d", is, test.expected)
+			}
+		})
+	}
+
+	l := DefaultLayout()
+	l.CopyrightPattern = ""
+	n, err := html.Parse(strings.NewReader("<p>© 2017</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is := copyrightYear(n, newOptions(SiteLayout(l))); is != 0 {
+		t.Errorf("copyright year found without pattern: %d", is)
+	}
+}
+
+func TestParseRunningOrderInferredYear(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := html.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := newOptions()
+	o.referenceYear = 2018
+
+	ro, err := parseDocument(n, o)
+	if err != nil {
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
+	}
+
+	if ro.Year != 2017 {
+		t.Errorf("unexpected year; is %d; expected %d", ro.Year, 2017)
+	}
+	t.Run("check timestamps", compareTimeStampsPointers(ro.Days[0].TimeStamps, &TimeStamps{Start: 1500674400, End: 1500760800}))
+}
+
+func TestParseRunningOrderYearConflict(t *testing.T) {
+	f, err := os.Open("./testdata/sample.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := html.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lenient := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lenient %t", lenient), func(t *testing.T) {
+			ro, err := parseDocument(n, newOptions(Year(2018), Lenient(lenient)))
+			if err != nil {
+				t.Fatalf("parseDocument returned an unexpected error: %v", err)
+			}
+
+			if ro.Year != 2018 {
+				t.Errorf("unexpected year; is %d; expected %d", ro.Year, 2018)
+			}
+			t.Run("check kinds", compareWarningKinds(ro.Warnings, KindYear, KindYear, KindYear))
+			t.Run("check timestamps", compareTimeStampsPointers(ro.Days[0].TimeStamps, &TimeStamps{Start: 1532210400, End: 1532296800}))
+		})
+	}
+}
+
+func TestYearTracker(t *testing.T) {
+	yt := &yearTracker{year: 2017}
+	o := newOptions()
+
+	ts := []struct {
+		label    string
+		expected int
+	}{
+		{"Saturday 30.12.", 2017},
+		{"Sunday 31.12.", 2017},
+		{"Monday 01.01.", 2018},
+		{"Tuesday 02.01.", 2018},
+		{"Someday 03.01.", 2018},
+		{"Monday 01.01.", 2018},
+	}
+
+	for _, test := range ts {
+		if is := yt.yearOf(test.label, o); is != test.expected {
+			t.Errorf("unexpected year for %q; is %d; expected %d", test.label, is, test.expected)
+		}
+	}
+}
+
+func TestParseRunningOrderNewYear(t *testing.T) {
+	doc := ""
+	for _, l := range []string{"Friday 29. 12.", "Saturday 30. 12.", "Sunday 31. 12.", "Monday 01. 01."} {
+		doc += "<div class='lineup_day'><div class='lineup_stage'><div class='l-stage'>" + l + "<span>Main</span></div></div></div>"
+	}
+
+	n, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := newOptions()
+	o.referenceYear = 2018
+
+	ro, err := parseDocument(n, o)
+	if err != nil {
+		t.Fatalf("parseDocument returned an unexpected error: %v", err)
+	}
+
+	if ro.Year != 2017 {
+		t.Errorf("unexpected year; is %d; expected %d", ro.Year, 2017)
+	}
+
+	expected := []*TimeStamps{
+		{Start: 1514502000, End: 1514588400},
+		{Start: 1514588400, End: 1514674800},
+		{Start: 1514674800, End: 1514761200},
+		{Start: 1514761200, End: 1514847600},
+	}
+
+	if len(ro.Days) != len(expected) {
+		t.Fatalf("unexpected number of days; is %d; expected %d", len(ro.Days), len(expected))
+	}
+	for i, d := range ro.Days {
+		t.Run(d.Label, compareTimeStampsPointers(d.TimeStamps, expected[i]))
+	}
+}